@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// Valid returns true until the token is close to its expiration time
+func (t Token) Valid() bool {
+	return t.Val != "" && time.Now().Before(t.expires)
+}
+
+// CachedTokenSource hands out the same token until it is close to its
+// expiration time, then refreshes it once. It is safe for concurrent use.
+type CachedTokenSource struct {
+	Credentials ClientCredentials
+
+	// CacheFile persists the tokens between runs when not empty
+	CacheFile string
+
+	mu    sync.Mutex
+	token Token
+}
+
+// the client refreshes the tokens rejected by Zuora
+var _ zuora.TokenInvalidator = (*CachedTokenSource)(nil)
+
+// NewCachedTokenSource returns a token source caching the tokens in memory,
+// and in the cache file if not empty
+func NewCachedTokenSource(credentials ClientCredentials, cacheFile string) *CachedTokenSource {
+	return &CachedTokenSource{
+		Credentials: credentials,
+		CacheFile:   cacheFile,
+	}
+}
+
+// Token returns the cached token value, refreshing it when needed
+func (s *CachedTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token.Val, nil
+	}
+
+	if token, ok := s.readCache(); ok {
+		s.token = token
+		return token.Val, nil
+	}
+
+	token, err := s.Credentials.NewToken()
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+
+	// failing to persist the token only costs a new token on the next run
+	_ = s.writeCache(token)
+
+	return token.Val, nil
+}
+
+// Invalidate discards the token rejected by Zuora, from memory and from the
+// cache file, so that the next call to Token generates a new one
+func (s *CachedTokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Val == token {
+		s.token = Token{}
+	}
+
+	if s.CacheFile == "" {
+		return
+	}
+
+	cache := s.loadCache()
+	if cached, ok := cache[s.cacheKey()]; ok && cached.AccessToken == token {
+		delete(cache, s.cacheKey())
+		_ = s.saveCache(cache)
+	}
+}
+
+type cachedToken struct {
+	AccessToken string    `json:"accessToken"`
+	Expires     time.Time `json:"expires"`
+}
+
+// cacheKey isolates the tokens of each tenant and OAuth client
+func (s *CachedTokenSource) cacheKey() string {
+	return s.Credentials.BaseURL + " " + s.Credentials.ClientID
+}
+
+func (s *CachedTokenSource) loadCache() map[string]cachedToken {
+	result := make(map[string]cachedToken)
+
+	content, err := ioutil.ReadFile(s.CacheFile)
+	if err != nil {
+		return result
+	}
+
+	if err := json.Unmarshal(content, &result); err != nil {
+		return make(map[string]cachedToken)
+	}
+
+	return result
+}
+
+func (s *CachedTokenSource) readCache() (Token, bool) {
+	if s.CacheFile == "" {
+		return Token{}, false
+	}
+
+	cached, ok := s.loadCache()[s.cacheKey()]
+	if !ok {
+		return Token{}, false
+	}
+
+	token := Token{Val: cached.AccessToken, expires: cached.Expires}
+	return token, token.Valid()
+}
+
+func (s *CachedTokenSource) writeCache(token Token) error {
+	if s.CacheFile == "" {
+		return nil
+	}
+
+	cache := s.loadCache()
+	for key, cached := range cache {
+		if time.Now().After(cached.Expires) {
+			delete(cache, key)
+		}
+	}
+	cache[s.cacheKey()] = cachedToken{AccessToken: token.Val, Expires: token.expires}
+
+	return s.saveCache(cache)
+}
+
+func (s *CachedTokenSource) saveCache(cache map[string]cachedToken) error {
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.CacheFile), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.CacheFile, content, 0600)
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedTokenSource(t *testing.T) {
+	newServer := func(calls *int) *httptest.Server {
+		var mu sync.Mutex
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			*calls++
+			n := *calls
+			mu.Unlock()
+
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3599}`, n)
+		}))
+	}
+
+	t.Run("reuses the token until it expires", func(t *testing.T) {
+		calls := 0
+		server := newServer(&calls)
+		defer server.Close()
+
+		source := NewCachedTokenSource(ClientCredentials{BaseURL: server.URL}, "")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := source.Token(); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if calls != 1 {
+			t.Errorf("got %d calls to /oauth/token want 1", calls)
+		}
+
+		source.token.expires = source.token.expires.Add(-2 * time.Hour)
+		got, err := source.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != "token-2" || calls != 2 {
+			t.Errorf("got %q after %d calls want token-2 after 2 calls", got, calls)
+		}
+	})
+
+	t.Run("reuses the token from the cache file", func(t *testing.T) {
+		calls := 0
+		server := newServer(&calls)
+		defer server.Close()
+
+		cacheFile := filepath.Join(t.TempDir(), "znt", "token.json")
+		credentials := ClientCredentials{BaseURL: server.URL, ClientID: "client"}
+
+		first, err := NewCachedTokenSource(credentials, cacheFile).Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		second, err := NewCachedTokenSource(credentials, cacheFile).Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first != second || calls != 1 {
+			t.Errorf("got %q then %q after %d calls want the same token after 1 call", first, second, calls)
+		}
+
+		credentials.ClientID = "another"
+		if _, err := NewCachedTokenSource(credentials, cacheFile).Token(); err != nil {
+			t.Fatal(err)
+		}
+
+		if calls != 2 {
+			t.Errorf("got %d calls want a new token for another client", calls)
+		}
+	})
+
+	t.Run("invalidates the rejected token", func(t *testing.T) {
+		calls := 0
+		server := newServer(&calls)
+		defer server.Close()
+
+		cacheFile := filepath.Join(t.TempDir(), "token.json")
+		credentials := ClientCredentials{BaseURL: server.URL, ClientID: "client"}
+
+		source := NewCachedTokenSource(credentials, cacheFile)
+		first, err := source.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		source.Invalidate("another token")
+		if got, _ := source.Token(); got != first || calls != 1 {
+			t.Errorf("got %q after %d calls want %q to be kept", got, calls, first)
+		}
+
+		source.Invalidate(first)
+
+		// the next run must not read the revoked token from the cache file
+		second, err := NewCachedTokenSource(credentials, cacheFile).Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if second == first || calls != 2 {
+			t.Errorf("got %q after %d calls want a new token", second, calls)
+		}
+	})
+}
@@ -2,13 +2,12 @@ package auth
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/mickaelpham/znt/zuora"
 )
 
 // Token is an OAuth token from Zuora with an expiration time
@@ -22,35 +21,50 @@ type createTokenResponse struct {
 	ExpiresIn   int    `json:"expires_in"`
 }
 
+// ClientCredentials generates OAuth tokens for a Zuora OAuth client
+type ClientCredentials struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	HTTPClient   *http.Client
+}
+
 // NewToken generates a new token from Zuora
-func NewToken() Token {
+func (c ClientCredentials) NewToken() (Token, error) {
 	form := url.Values{}
-	form.Set("client_id", viper.GetString("client"))
-	form.Set("client_secret", viper.GetString("secret"))
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
 	form.Set("grant_type", "client_credentials")
 
-	response, err := http.PostForm(viper.GetString("baseurl")+"/oauth/token", form)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.PostForm(strings.TrimSuffix(c.BaseURL, "/")+"/oauth/token", form)
 	if err != nil {
-		log.Fatal(err)
+		return Token{}, err
 	}
 	defer response.Body.Close()
 
-	if response.StatusCode != 200 {
-		body, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-		log.Fatal(string(body))
+	if err := zuora.CheckResponse(response); err != nil {
+		return Token{}, err
 	}
 
 	dec := json.NewDecoder(response.Body)
 	var body createTokenResponse
 	if err = dec.Decode(&body); err != nil {
-		log.Fatal(err)
+		return Token{}, err
 	}
 
 	return Token{
 		Val:     body.AccessToken,
 		expires: time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - 15*time.Minute),
-	}
+	}, nil
+}
+
+// Token generates a new token and returns its value, it implements zuora.TokenSource
+func (c ClientCredentials) Token() (string, error) {
+	token, err := c.NewToken()
+	return token.Val, err
 }
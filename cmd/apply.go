@@ -1,46 +1,98 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"log"
 	"os"
 
-	"github.com/manifoldco/promptui"
 	"github.com/mickaelpham/znt/diff"
 	"github.com/spf13/cobra"
 )
 
-var applyCmd = &cobra.Command{
-	Use:   "apply",
-	Short: "Apply the diff",
-	Long: `
+var (
+	// used for flags
+	keepInactive bool
+
+	applyCmd = &cobra.Command{
+		Use:   "apply [planfile]",
+		Short: "Apply the diff",
+		Long: `
 Apply the triggers diff and notification diff to
-the targeted Zuora environment`,
-	Run: func(cmd *cobra.Command, args []string) {
-		f, err := os.Open(tplFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		tpl, err := diff.Parse(bufio.NewReader(f))
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		triggerDiff := diff.NewTriggerDiff(tpl.Triggers(), diff.FetchManagedTriggers())
-		fmt.Println(triggerDiff)
-
-		prompt := promptui.Prompt{
-			Label:     "Apply changes to Zuora",
-			IsConfirm: true,
-		}
-
-		proceed, _ := prompt.Run()
-		if proceed != "y" {
-			return
-		}
-
-		triggerDiff.Apply()
-	},
+the targeted Zuora environment. When given a plan file
+saved by "znt plan --out", apply it without prompting
+unless the environment changed since the plan was made,
+or the environment requires typing its name to confirm.
+The inactive resources are reactivated, unless
+--keep-inactive is set.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printEnvironment()
+			client := newClient()
+
+			remote, err := diff.FetchRemote(client)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				plan, err := readPlan(args[0])
+				if err != nil {
+					return err
+				}
+
+				if err := plan.Verify(remote, client.BaseURL); err != nil {
+					return fmt.Errorf("%w, create a new plan", err)
+				}
+
+				if keepInactive {
+					plan.KeepInactive()
+				}
+
+				fmt.Println(plan)
+
+				// the plan was approved when it was saved, but not for this
+				// environment: the plan file does not record its name
+				if confirmEnvironment() && !confirm("Apply the plan to Zuora") {
+					return nil
+				}
+
+				return plan.Apply(client)
+			}
+
+			tpl, err := loadTemplate()
+			if err != nil {
+				return err
+			}
+
+			plan, err := diff.NewPlan(tpl, remote, client.BaseURL)
+			if err != nil {
+				return err
+			}
+
+			if keepInactive {
+				plan.KeepInactive()
+			}
+
+			fmt.Println(plan)
+
+			if !confirm("Apply changes to Zuora") {
+				return nil
+			}
+
+			return plan.Apply(client)
+		},
+	}
+)
+
+func init() {
+	applyCmd.Flags().BoolVar(&keepInactive, "keep-inactive", false, "leave the inactive resources inactive")
+}
+
+func readPlan(path string) (*diff.Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return diff.ReadPlan(f)
 }
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/manifoldco/promptui"
+)
+
+// confirmEnvironment returns true when the environment is configured with
+// "confirm: true", its changes are approved by typing its name
+func confirmEnvironment() bool {
+	return envName != "" && settingBool("confirm")
+}
+
+// confirm asks the user to approve the changes before they are applied. An
+// environment configured with "confirm: true" requires typing its name.
+func confirm(label string) bool {
+	if confirmEnvironment() {
+		prompt := promptui.Prompt{
+			Label: label + ", type " + envName + " to confirm",
+			Validate: func(input string) error {
+				if input != envName {
+					return errors.New("type " + envName + " to confirm")
+				}
+				return nil
+			},
+		}
+
+		typed, err := prompt.Run()
+		return err == nil && typed == envName
+	}
+
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+
+	proceed, _ := prompt.Run()
+	return proceed == "y"
+}
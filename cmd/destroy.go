@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mickaelpham/znt/diff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// used for flags
+	destroyBaseObject  string
+	destroyTriggerName string
+
+	destroyCmd = &cobra.Command{
+		Use:   "destroy",
+		Short: "Destroy everything managed by znt",
+		Long: `
+Delete every trigger, scheduled event, custom event type,
+notification definition and email template managed by znt
+from the targeted Zuora environment, optionally restricted
+to a base object and a trigger or scheduled event name.
+The communication profiles are left in place, as customer
+accounts reference them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if destroyTriggerName != "" && destroyBaseObject == "" {
+				return errors.New("--trigger requires --base-object")
+			}
+
+			printEnvironment()
+			client := newClient()
+
+			triggers, err := diff.FetchManagedTriggers(client)
+			if err != nil {
+				return err
+			}
+
+			notifications, err := diff.FetchManagedNotifications(client)
+			if err != nil {
+				return err
+			}
+
+			emailTemplates, err := diff.FetchManagedEmailTemplates(client)
+			if err != nil {
+				return err
+			}
+
+			eventTypes, err := diff.FetchManagedCustomEventTypes(client)
+			if err != nil {
+				return err
+			}
+
+			scheduledEvents, err := diff.FetchManagedScheduledEvents(client)
+			if err != nil {
+				return err
+			}
+
+			// the custom event types have no base object, a filtered destroy
+			// leaves them and their notifications alone
+			custom := make(map[string]bool)
+			for _, e := range eventTypes {
+				custom[e.Name] = true
+			}
+
+			triggerDiff := diff.TriggerDiff{}
+			for _, t := range triggers {
+				if destroyMatches(t.EventType.Name, custom) {
+					triggerDiff.Remove = append(triggerDiff.Remove, t)
+				}
+			}
+			fmt.Println(triggerDiff)
+
+			notificationDiff := diff.NotificationDiff{}
+			for _, n := range notifications {
+				if destroyMatches(n.EventTypeName, custom) {
+					notificationDiff.Remove = append(notificationDiff.Remove, n)
+				}
+			}
+			fmt.Println(notificationDiff)
+
+			emailTemplateDiff := diff.EmailTemplateDiff{}
+			for _, e := range emailTemplates {
+				if destroyMatches(e.EventTypeName, custom) {
+					emailTemplateDiff.Remove = append(emailTemplateDiff.Remove, e)
+				}
+			}
+			fmt.Println(emailTemplateDiff)
+
+			eventTypeDiff := diff.CustomEventTypeDiff{}
+			for _, e := range eventTypes {
+				if destroyMatches(e.Name, custom) {
+					eventTypeDiff.Remove = append(eventTypeDiff.Remove, e)
+				}
+			}
+			fmt.Println(eventTypeDiff)
+
+			scheduledEventDiff := diff.ScheduledEventDiff{}
+			for _, s := range scheduledEvents {
+				if destroyMatches(s.Name, custom) {
+					scheduledEventDiff.Remove = append(scheduledEventDiff.Remove, s)
+				}
+			}
+			fmt.Println(scheduledEventDiff)
+
+			if len(triggerDiff.Remove) == 0 && len(notificationDiff.Remove) == 0 &&
+				len(emailTemplateDiff.Remove) == 0 && len(eventTypeDiff.Remove) == 0 &&
+				len(scheduledEventDiff.Remove) == 0 {
+				fmt.Println("Nothing to destroy.")
+				return nil
+			}
+
+			if !confirm("Destroy these resources in Zuora") {
+				return nil
+			}
+
+			// notifications reference the email templates and the event types
+			if err := notificationDiff.ApplyRemove(client); err != nil {
+				return err
+			}
+
+			if err := emailTemplateDiff.ApplyRemove(client); err != nil {
+				return err
+			}
+
+			if err := eventTypeDiff.ApplyRemove(client); err != nil {
+				return err
+			}
+
+			if err := scheduledEventDiff.ApplyRemove(client); err != nil {
+				return err
+			}
+
+			return triggerDiff.Apply(client)
+		},
+	}
+)
+
+func init() {
+	destroyCmd.Flags().StringVarP(&destroyBaseObject, "base-object", "b", "", "only destroy the resources of this base object")
+	destroyCmd.Flags().StringVarP(&destroyTriggerName, "trigger", "n", "", "only destroy the resources of this trigger name (requires --base-object)")
+}
+
+// destroyMatches returns true when the event type name matches the destroy
+// filters, the trigger name also matches the scheduled event names. The
+// custom event types only match without filters.
+func destroyMatches(eventTypeName string, custom map[string]bool) bool {
+	if destroyBaseObject == "" {
+		return true
+	}
+
+	if custom[eventTypeName] {
+		return false
+	}
+
+	if destroyTriggerName != "" {
+		return eventTypeName == diff.NewTrigger(destroyBaseObject, destroyTriggerName, "").EventType.Name ||
+			eventTypeName == diff.ScheduledEventName(destroyBaseObject, destroyTriggerName)
+	}
+
+	return strings.HasPrefix(eventTypeName, diff.ScheduledEventName(destroyBaseObject, ""))
+}
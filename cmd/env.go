@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// selectEnvironment picks the --env environment, or the configured default
+// one, and checks it is defined in the config file
+func selectEnvironment(cmd *cobra.Command, args []string) error {
+	if envName == "" {
+		envName = viper.GetString("defaultenv")
+	}
+
+	environments := viper.GetStringMap("environments")
+	if envName != "" && len(environments) > 0 && !viper.IsSet("environments."+envName) {
+		names := make([]string, 0, len(environments))
+		for name := range environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("environment %q is not defined in %s, expected one of: %s", envName, viper.ConfigFileUsed(), strings.Join(names, ", "))
+	}
+
+	if tplFile == "" {
+		tplFile = setting("template")
+	}
+
+	return nil
+}
+
+// setting returns the value of the key for the active environment, falling
+// back to the top-level value of the config file
+func setting(key string) string {
+	if envName != "" {
+		if envKey := "environments." + envName + "." + key; viper.IsSet(envKey) {
+			return viper.GetString(envKey)
+		}
+	}
+
+	return viper.GetString(key)
+}
+
+// settingBool is setting for the boolean keys
+func settingBool(key string) bool {
+	if envName != "" {
+		if envKey := "environments." + envName + "." + key; viper.IsSet(envKey) {
+			return viper.GetBool(envKey)
+		}
+	}
+
+	return viper.GetBool(key)
+}
+
+// printEnvironment shows the targeted environment before changing it
+func printEnvironment() {
+	name := envName
+	if name == "" {
+		name = "(default)"
+	}
+
+	fmt.Fprintf(os.Stderr, "\n==> Environment: %s\n==> Zuora: %s\n\n", strings.ToUpper(name), setting("baseurl"))
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mickaelpham/znt/diff"
+	"github.com/spf13/cobra"
+)
+
+// used for flags
+var outputFormat string
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format, text or json")
+
+	// fail before querying Zuora
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unknown output format %q, expected text or json", outputFormat)
+		}
+
+		return nil
+	}
+}
+
+// printPlan writes the plan to stdout in the requested output format, the
+// logs are written to stderr so the JSON output can be piped
+func printPlan(plan *diff.Plan, remote *diff.Remote, tpl *diff.Template) error {
+	overrides := tpl.CalloutOverrides()
+
+	// the profiles to create are selected with a pending ID
+	selections, err := tpl.ProfileSelections(plan.CommunicationProfiles.ProfileIDs(remote.Profiles))
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		report := diff.NewReport(plan, remote.Profiles)
+		report.CalloutOverrides = overrides
+		report.ProfileSelections = selections
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(tpl.CommunicationProfiles) > 0 || len(plan.CommunicationProfiles.Remove) > 0 {
+		fmt.Println(plan.CommunicationProfiles)
+	}
+
+	fmt.Println(plan.Triggers)
+
+	if len(tpl.ScheduledEvents) > 0 || len(remote.ScheduledEvents) > 0 {
+		fmt.Println(plan.ScheduledEvents)
+	}
+
+	if len(tpl.EventTypes) > 0 || len(remote.EventTypes) > 0 {
+		fmt.Println(plan.EventTypes)
+	}
+
+	if len(tpl.EmailTemplates) > 0 || len(remote.EmailTemplates) > 0 {
+		fmt.Println(plan.EmailTemplates)
+	}
+
+	fmt.Println("--- Communication Profiles")
+	for _, selection := range selections {
+		if selection.Notification != "" {
+			fmt.Printf("\n%s notification:\n", selection.Notification)
+		}
+
+		for _, p := range selection.Profiles {
+			fmt.Printf("  * (%s) %s [%s]\n", p.ID, p.Name, p.Rule)
+		}
+	}
+	fmt.Println()
+
+	fmt.Println(plan.Notifications)
+
+	if len(overrides) > 0 {
+		names := make([]string, 0, len(overrides))
+		for name := range overrides {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Println("--- Callout Overrides")
+		for _, name := range names {
+			fmt.Printf("  * %s: %s\n", name, overrides[name])
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
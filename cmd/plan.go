@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickaelpham/znt/diff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// used for flags
+	planFile string
+
+	planCmd = &cobra.Command{
+		Use:   "plan",
+		Short: "Save the diff to a plan file",
+		Long: `
+Compute the triggers diff and notification diff, and
+save them to a plan file to be applied later with
+"znt apply <planfile>". The plan file contains the
+callout credentials of the template.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tpl, err := loadTemplate()
+			if err != nil {
+				return err
+			}
+
+			client := newClient()
+
+			remote, err := diff.FetchRemote(client)
+			if err != nil {
+				return err
+			}
+
+			plan, err := diff.NewPlan(tpl, remote, client.BaseURL)
+			if err != nil {
+				return err
+			}
+
+			if keepInactive {
+				plan.KeepInactive()
+			}
+
+			if err := printPlan(plan, remote, tpl); err != nil {
+				return err
+			}
+
+			if planFile == "" {
+				return nil
+			}
+
+			f, err := os.OpenFile(planFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			if err := diff.WritePlan(f, plan); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Plan saved to %s, apply it with: znt apply %s\n", planFile, planFile)
+			return f.Close()
+		},
+	}
+)
+
+func init() {
+	planCmd.Flags().StringVar(&planFile, "out", "", "write the plan to this file")
+	planCmd.Flags().BoolVar(&keepInactive, "keep-inactive", false, "leave the inactive resources inactive")
+	addOutputFlag(planCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// used for flags
+	renderFormat  string
+	renderSecrets bool
+
+	renderCmd = &cobra.Command{
+		Use:   "render",
+		Short: "Print the merged template",
+		Long: `
+Print the template used by verify and apply, once its
+files are merged, the overlay of the --env environment
+applied and its variables resolved.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if renderFormat != "json" && renderFormat != "yaml" {
+				return fmt.Errorf("unknown output format %q, expected json or yaml", renderFormat)
+			}
+
+			tpl, err := loadTemplate()
+			if err != nil {
+				return err
+			}
+
+			if !renderSecrets {
+				tpl.RedactSecrets()
+			}
+
+			content, err := json.MarshalIndent(tpl, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			if renderFormat == "json" {
+				fmt.Println(string(content))
+				return nil
+			}
+
+			// decoding the JSON as a YAML node preserves the order of the fields
+			var node yaml.Node
+			if err := yaml.Unmarshal(content, &node); err != nil {
+				return err
+			}
+			blockStyle(&node)
+
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(&node); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+)
+
+func init() {
+	renderCmd.Flags().StringVarP(&renderFormat, "output", "o", "json", "output format, json or yaml")
+	renderCmd.Flags().BoolVar(&renderSecrets, "show-secrets", false, "print the callout passwords")
+}
+
+// blockStyle resets the JSON flow and quoted styles of the node and its
+// children, the encoder still quotes the strings which need to be
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
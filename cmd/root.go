@@ -14,10 +14,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/mickaelpham/znt/auth"
+	"github.com/mickaelpham/znt/diff"
+	"github.com/mickaelpham/znt/zuora"
 	"github.com/spf13/cobra"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -28,6 +36,7 @@ var (
 	// used for flags
 	cfgFile string
 	tplFile string
+	envName string
 
 	// rootCmd represents the base command when called without any subcommands
 	rootCmd = &cobra.Command{
@@ -39,26 +48,65 @@ Zuora Notifications Terraform (ZNT)
 A manager for Zuora notification definitions
 built in Go. Complete documentation is available at
 https://github.com/mickaelpham/znt`,
+		SilenceErrors:     true,
+		SilenceUsage:      true,
+		PersistentPreRunE: selectEnvironment,
 	}
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SetArgs(terraformArgs(os.Args[1:]))
+
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		var code exitCode
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
+
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// terraformArgs rewrites the Terraform style "-out" flag of "znt plan" to
+// "--out", pflag would otherwise parse it as "-o ut"
+func terraformArgs(args []string) []string {
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+
+		if arg == "-out" || strings.HasPrefix(arg, "-out=") {
+			arg = "-" + arg
+		}
+		result = append(result, arg)
+	}
+
+	return result
+}
+
+// exitCode is returned by a command to exit with that code without printing an error
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit code %d", int(c))
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is $HOME/.znt.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&tplFile, "template", "t", "", "template file")
+	rootCmd.PersistentFlags().StringVarP(&tplFile, "template", "t", "", "template file, in JSON or YAML, or directory of template files")
+	rootCmd.PersistentFlags().StringVarP(&envName, "env", "e", "", "environment, selects the credentials and the template overlay")
 
 	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(destroyCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(renderCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -70,7 +118,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
@@ -81,8 +129,87 @@ func initConfig() {
 
 	viper.AutomaticEnv() // read in environment variables that match
 
+	viper.SetDefault("timeout", 60*time.Second)
+	viper.SetDefault("retry.max", zuora.DefaultRetryPolicy.MaxRetries)
+	viper.SetDefault("retry.minwait", zuora.DefaultRetryPolicy.MinWait)
+	viper.SetDefault("retry.maxwait", zuora.DefaultRetryPolicy.MaxWait)
+
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		log.Println("Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// newClient returns a Zuora client for the configured environment
+func newClient() *zuora.Client {
+	baseURL := setting("baseurl")
+
+	httpClient := &http.Client{Timeout: viper.GetDuration("timeout")}
+
+	credentials := auth.ClientCredentials{
+		BaseURL:      baseURL,
+		ClientID:     setting("client"),
+		ClientSecret: setting("secret"),
+		HTTPClient:   httpClient,
+	}
+
+	// reuse the token between runs when "tokencache: true" is configured
+	cacheFile := ""
+	if viper.GetBool("tokencache") {
+		if home, err := homedir.Dir(); err == nil {
+			cacheFile = filepath.Join(home, ".znt", "token.json")
+		}
+	}
+
+	client := zuora.NewClient(baseURL, auth.NewCachedTokenSource(credentials, cacheFile))
+	client.HTTPClient = httpClient
+	client.Retry = zuora.RetryPolicy{
+		MaxRetries: viper.GetInt("retry.max"),
+		MinWait:    viper.GetDuration("retry.minwait"),
+		MaxWait:    viper.GetDuration("retry.maxwait"),
+	}
+	client.Log = log.New(os.Stderr, "", log.LstdFlags)
+
+	return client
+}
+
+// loadTemplate parses the template file given with --template, applies the
+// overlay of the --env environment, and resolves its variables from the
+// environment, then from the config file
+func loadTemplate() (*diff.Template, error) {
+	tpl, err := diff.ParseFile(tplFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if envName != "" {
+		overlay, err := diff.OverlayPath(tplFile, envName)
+		if err != nil {
+			return nil, err
+		}
+
+		if overlay != "" {
+			log.Printf("Using %s overlay: %s\n", envName, overlay)
+			if tpl, err = tpl.ApplyOverlay(overlay); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = tpl.Interpolate(func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+
+		if viper.IsSet(name) {
+			return viper.GetString(name), true
+		}
+
+		return "", false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tpl, nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerraformArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"plan", "-out", "znt.plan"}, []string{"plan", "--out", "znt.plan"}},
+		{[]string{"plan", "-out=znt.plan", "-o", "json"}, []string{"plan", "--out=znt.plan", "-o", "json"}},
+		{[]string{"plan", "--out", "znt.plan"}, []string{"plan", "--out", "znt.plan"}},
+		{[]string{"apply", "--", "-out"}, []string{"apply", "--", "-out"}},
+	}
+
+	for _, tt := range tests {
+		if got := terraformArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terraformArgs(%q): got %q want %q", tt.args, got, tt.want)
+		}
+	}
+
+	t.Run("plan saves to the -out file", func(t *testing.T) {
+		defer func() { planFile = "" }()
+
+		if err := planCmd.ParseFlags(terraformArgs([]string{"-out", "znt.plan"})); err != nil {
+			t.Fatal(err)
+		}
+
+		if planFile != "znt.plan" || outputFormat != "text" {
+			t.Errorf("got plan file %q and output %q want znt.plan and text", planFile, outputFormat)
+		}
+	})
+}
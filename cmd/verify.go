@@ -1,54 +1,58 @@
 package cmd
 
 import (
-	"bufio"
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/mickaelpham/znt/diff"
 	"github.com/spf13/cobra"
 )
 
+var (
+	// used for flags
+	detailedExitCode  bool
+	ignoreActivations bool
+)
+
 var verifyCmd = &cobra.Command{
 	Use:   "verify",
 	Short: "Verify notifications exist",
 	Long: `
 Query all notification definitions for the given Zuora
-environment, and verify they match the template.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// triggers := diff.FetchManagedTriggers()
-		// fmt.Printf("Found %d event triggers\n", len(triggers))
+environment, and verify they match the template.
 
-		// notifications := diff.FetchManagedNotifications()
-		// fmt.Printf("Found %d notification\n", len(notifications))
-		// fmt.Println(notifications)
+With --detailed-exitcode, exit with 0 when the environment
+matches the template, 2 when changes are pending and 1 on
+errors.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tpl, err := loadTemplate()
+		if err != nil {
+			return err
+		}
 
-		// profiles := diff.FetchProfiles()
-		// fmt.Printf("Found %d communication profiles\n", len(profiles))
-		// fmt.Println(profiles)
+		client := newClient()
 
-		f, err := os.Open(tplFile)
+		remote, err := diff.FetchRemote(client)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		tpl, err := diff.Parse(bufio.NewReader(f))
+		plan, err := diff.NewPlan(tpl, remote, client.BaseURL)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		triggerDiff := diff.NewTriggerDiff(tpl.Triggers(), diff.FetchManagedTriggers())
-		fmt.Println(triggerDiff)
+		if err := printPlan(plan, remote, tpl); err != nil {
+			return err
+		}
 
-		profiles := diff.FetchProfiles()
-		fmt.Println("--- Communication Profiles")
-		for name, ID := range profiles {
-			fmt.Printf("  * (%s) %s\n", ID, name)
+		if detailedExitCode && plan.Pending(ignoreActivations) {
+			return exitCode(2)
 		}
-		fmt.Println()
 
-		notificationDiff := diff.NewNotificationDiff(tpl.NotificationDefinitions(profiles), diff.FetchManagedNotifications())
-		fmt.Println(notificationDiff)
+		return nil
 	},
 }
+
+func init() {
+	addOutputFlag(verifyCmd)
+	verifyCmd.Flags().BoolVar(&detailedExitCode, "detailed-exitcode", false, "exit with 2 when changes are pending")
+	verifyCmd.Flags().BoolVar(&ignoreActivations, "ignore-activations", false, "with --detailed-exitcode, ignore the updates which only reactivate disabled resources")
+}
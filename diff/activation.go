@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ActivationError lists the resources which failed to be reactivated, a
+// failed activation does not stop the other changes
+type ActivationError struct {
+	Failed []string
+}
+
+func (e *ActivationError) Error() string {
+	return fmt.Sprintf("failed to activate %d resource(s): %s", len(e.Failed), strings.Join(e.Failed, ", "))
+}
+
+// activations reports the reactivation of each resource
+type activations struct {
+	failed []string
+}
+
+func (a *activations) report(resource string, err error) {
+	if err != nil {
+		log.Printf("Failed to activate %s: %v", resource, err)
+		a.failed = append(a.failed, resource)
+		return
+	}
+
+	log.Printf("Activated %s", resource)
+}
+
+// merge collects the failed activations of err, and returns the other errors
+func (a *activations) merge(err error) error {
+	if e, ok := err.(*ActivationError); ok {
+		a.failed = append(a.failed, e.Failed...)
+		return nil
+	}
+
+	return err
+}
+
+func (a *activations) err() error {
+	if len(a.failed) == 0 {
+		return nil
+	}
+
+	return &ActivationError{Failed: a.failed}
+}
+
+// keepInactive leaves an inactive remote resource inactive, it returns false
+// when the update only reactivates it
+func keepInactive(remoteActive bool, templateActive *bool, fields *[]string) bool {
+	if remoteActive {
+		return true
+	}
+
+	*templateActive = false
+	result := make([]string, 0, len(*fields))
+	for _, field := range *fields {
+		if field != "Active" {
+			result = append(result, field)
+		}
+	}
+	*fields = result
+
+	return len(result) > 0
+}
+
+// KeepInactive leaves the inactive remote resources inactive: the updates
+// which only reactivate them are dropped, the other updates keep them inactive
+func (p *Plan) KeepInactive() {
+	triggers := p.Triggers.Update[:0]
+	for _, u := range p.Triggers.Update {
+		if keepInactive(u.Remote.Active, &u.Template.Active, &u.Fields) {
+			triggers = append(triggers, u)
+		}
+	}
+	p.Triggers.Update = triggers
+
+	notifications := p.Notifications.Update[:0]
+	for _, u := range p.Notifications.Update {
+		if keepInactive(u.Remote.Active, &u.Template.Active, &u.Fields) {
+			notifications = append(notifications, u)
+		}
+	}
+	p.Notifications.Update = notifications
+
+	emailTemplates := p.EmailTemplates.Update[:0]
+	for _, u := range p.EmailTemplates.Update {
+		if keepInactive(u.Remote.Active, &u.Template.Active, &u.Fields) {
+			emailTemplates = append(emailTemplates, u)
+		}
+	}
+	p.EmailTemplates.Update = emailTemplates
+
+	eventTypes := p.EventTypes.Update[:0]
+	for _, u := range p.EventTypes.Update {
+		if keepInactive(u.Remote.Active, &u.Template.Active, &u.Fields) {
+			eventTypes = append(eventTypes, u)
+		}
+	}
+	p.EventTypes.Update = eventTypes
+
+	scheduledEvents := p.ScheduledEvents.Update[:0]
+	for _, u := range p.ScheduledEvents.Update {
+		if keepInactive(u.Remote.Active, &u.Template.Active, &u.Fields) {
+			scheduledEvents = append(scheduledEvents, u)
+		}
+	}
+	p.ScheduledEvents.Update = scheduledEvents
+}
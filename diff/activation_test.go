@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+type staticToken string
+
+func (t staticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+func TestApplyActivations(t *testing.T) {
+	// the updates of the resources with a "fail" ID are rejected
+	newClient := func(t *testing.T) *zuora.Client {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PUT" {
+				t.Errorf("got %s %s want only updates", r.Method, r.URL.Path)
+			}
+
+			if strings.HasSuffix(r.URL.Path, "/fail") {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			w.Write([]byte(`{}`))
+		}))
+		t.Cleanup(server.Close)
+
+		return zuora.NewClient(server.URL, staticToken("secret"))
+	}
+
+	captureLog := func(t *testing.T) *bytes.Buffer {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		t.Cleanup(func() { log.SetOutput(os.Stderr) })
+		return &buf
+	}
+
+	inactive := func(id, condition string) TriggerUpdate {
+		return TriggerUpdate{
+			Remote:   Trigger{ID: id, BaseObject: "Account", Condition: condition},
+			Template: Trigger{Active: true, BaseObject: "Account", Condition: condition},
+			Fields:   []string{"Active"},
+		}
+	}
+
+	t.Run("reports each activation and applies the other changes", func(t *testing.T) {
+		logs := captureLog(t)
+
+		plan := &Plan{
+			Triggers: TriggerDiff{Update: []TriggerUpdate{
+				inactive("ok", "changeType == 'INSERT'"),
+				inactive("fail", "changeType == 'UPDATE'"),
+				{
+					Remote:   Trigger{ID: "active", Active: true, BaseObject: "Account", Condition: "changeType == 'DELETE'"},
+					Template: Trigger{Active: true, BaseObject: "Account", Condition: "changeType == 'DELETE' && Account.Status == 'Active'"},
+					Fields:   []string{"Condition"},
+				},
+			}},
+			Notifications: NotificationDiff{Update: []NotificationUpdate{{
+				Remote:   Notification{ID: "ok", CommunicationProfileID: "p1", EventTypeName: "znt-Account-onInsert"},
+				Template: Notification{Active: true, CommunicationProfileID: "p1", EventTypeName: "znt-Account-onInsert"},
+				Fields:   []string{"Active"},
+			}}},
+		}
+
+		err := plan.Apply(newClient(t))
+
+		var activation *ActivationError
+		if !errors.As(err, &activation) {
+			t.Fatalf("got %v want an activation error", err)
+		}
+
+		if want := []string{`trigger {Account on "changeType == 'UPDATE'"}`}; !reflect.DeepEqual(activation.Failed, want) {
+			t.Errorf("Failed: got %v want %v", activation.Failed, want)
+		}
+
+		for _, want := range []string{
+			`Activated trigger {Account on "changeType == 'INSERT'"}`,
+			`Failed to activate trigger {Account on "changeType == 'UPDATE'"}`,
+			`Activated notification (p1) znt-Account-onInsert`,
+		} {
+			if !strings.Contains(logs.String(), want) {
+				t.Errorf("got logs %q want %q", logs.String(), want)
+			}
+		}
+
+		if strings.Contains(logs.String(), "DELETE") {
+			t.Errorf("got logs %q want no report for the active trigger", logs.String())
+		}
+	})
+
+	t.Run("stops on the updates of the resources kept inactive", func(t *testing.T) {
+		logs := captureLog(t)
+
+		plan := &Plan{Triggers: TriggerDiff{Update: []TriggerUpdate{
+			{
+				Remote:   Trigger{ID: "fail", BaseObject: "Account", Condition: "changeType == 'INSERT'"},
+				Template: Trigger{Active: true, BaseObject: "Account", Condition: "changeType == 'UPDATE'"},
+				Fields:   []string{"Active", "Condition"},
+			},
+		}}}
+		plan.KeepInactive()
+
+		err := plan.Apply(newClient(t))
+
+		var activation *ActivationError
+		if err == nil || errors.As(err, &activation) {
+			t.Errorf("got %v want the update error", err)
+		}
+
+		if logs.Len() > 0 {
+			t.Errorf("got logs %q want no activation reported", logs.String())
+		}
+	})
+}
@@ -1,24 +1,253 @@
 package diff
 
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
 // Callout sent by Zuora
 type Callout struct {
-	Active         bool
-	CalloutAuth    CalloutAuth
-	CalloutBaseURL string
-	CalloutParams  map[string]string
-	CalloutRetry   bool
-	Description    string
-	EventTypeName  string
-	HTTPMethod     string
-	ID             string
-	Name           string
-	RequiredAuth   bool
+	Active         bool              `json:"active,omitempty"`
+	CalloutAuth    CalloutAuth       `json:"calloutAuth"`
+	CalloutBaseURL string            `json:"calloutBaseurl"`
+	CalloutParams  map[string]string `json:"calloutParams,omitempty"`
+	CalloutRetry   bool              `json:"calloutRetry,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	EventTypeName  string            `json:"eventTypeName,omitempty"`
+	HTTPMethod     string            `json:"httpMethod,omitempty"`
+	ID             string            `json:"id,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	RequiredAuth   bool              `json:"requiredAuth,omitempty"`
 }
 
 // CalloutAuth sent by Zuora
 type CalloutAuth struct {
-	Domain     string
-	Password   string
-	Preemptive bool
-	Username   string
+	Domain     string `json:"domain"`
+	Password   string `json:"password"`
+	Preemptive bool   `json:"preemptive"`
+	Username   string `json:"username"`
+}
+
+// CalloutOverride replaces the fields of the shared callout which are set,
+// for a notification or one of its triggers
+type CalloutOverride struct {
+	CalloutAuth    *CalloutAuth `json:"calloutAuth,omitempty"`
+	CalloutBaseURL *string      `json:"calloutBaseurl,omitempty"`
+	CalloutRetry   *bool        `json:"calloutRetry,omitempty"`
+	HTTPMethod     *string      `json:"httpMethod,omitempty"`
+	RequiredAuth   *bool        `json:"requiredAuth,omitempty"`
+}
+
+// apply returns the callout with the overridden fields
+func (o *CalloutOverride) apply(c Callout) Callout {
+	if o == nil {
+		return c
+	}
+
+	if o.CalloutAuth != nil {
+		c.CalloutAuth = *o.CalloutAuth
+	}
+
+	if o.CalloutBaseURL != nil {
+		c.CalloutBaseURL = *o.CalloutBaseURL
+	}
+
+	if o.CalloutRetry != nil {
+		c.CalloutRetry = *o.CalloutRetry
+	}
+
+	if o.HTTPMethod != nil {
+		c.HTTPMethod = *o.HTTPMethod
+	}
+
+	if o.RequiredAuth != nil {
+		c.RequiredAuth = *o.RequiredAuth
+	}
+
+	return c
+}
+
+// merge returns the override with the fields set by another override
+func (o *CalloutOverride) merge(another *CalloutOverride) *CalloutOverride {
+	if o == nil {
+		return another
+	}
+
+	if another == nil {
+		return o
+	}
+
+	result := *o
+	if another.CalloutAuth != nil {
+		result.CalloutAuth = another.CalloutAuth
+	}
+
+	if another.CalloutBaseURL != nil {
+		result.CalloutBaseURL = another.CalloutBaseURL
+	}
+
+	if another.CalloutRetry != nil {
+		result.CalloutRetry = another.CalloutRetry
+	}
+
+	if another.HTTPMethod != nil {
+		result.HTTPMethod = another.HTTPMethod
+	}
+
+	if another.RequiredAuth != nil {
+		result.RequiredAuth = another.RequiredAuth
+	}
+
+	return &result
+}
+
+// fields lists the overridden fields with their value, except the credentials
+func (o *CalloutOverride) fields() []string {
+	result := make([]string, 0)
+	if o == nil {
+		return result
+	}
+
+	if o.CalloutAuth != nil {
+		result = append(result, "calloutAuth="+o.CalloutAuth.Username)
+	}
+
+	if o.CalloutBaseURL != nil {
+		result = append(result, "calloutBaseurl="+*o.CalloutBaseURL)
+	}
+
+	if o.CalloutRetry != nil {
+		result = append(result, fmt.Sprintf("calloutRetry=%t", *o.CalloutRetry))
+	}
+
+	if o.HTTPMethod != nil {
+		result = append(result, "httpMethod="+*o.HTTPMethod)
+	}
+
+	if o.RequiredAuth != nil {
+		result = append(result, fmt.Sprintf("requiredAuth=%t", *o.RequiredAuth))
+	}
+
+	return result
+}
+
+func (o *CalloutOverride) String() string {
+	return strings.Join(o.fields(), " ")
+}
+
+// Changes lists the managed fields of the remote callout which differ from
+// this callout
+func (c Callout) Changes(remote Callout) []string {
+	result := make([]string, 0)
+
+	if c.Active != remote.Active {
+		result = append(result, "Active")
+	}
+
+	if c.CalloutBaseURL != remote.CalloutBaseURL {
+		result = append(result, "CalloutBaseURL")
+	}
+
+	if !(len(c.CalloutParams) == 0 && len(remote.CalloutParams) == 0) && !reflect.DeepEqual(c.CalloutParams, remote.CalloutParams) {
+		result = append(result, "CalloutParams")
+	}
+
+	if c.CalloutRetry != remote.CalloutRetry {
+		result = append(result, "CalloutRetry")
+	}
+
+	if c.Description != remote.Description {
+		result = append(result, "Description")
+	}
+
+	if c.EventTypeName != remote.EventTypeName {
+		result = append(result, "EventTypeName")
+	}
+
+	if c.HTTPMethod != remote.HTTPMethod {
+		result = append(result, "HTTPMethod")
+	}
+
+	if c.Name != remote.Name {
+		result = append(result, "Name")
+	}
+
+	if c.RequiredAuth != remote.RequiredAuth {
+		result = append(result, "RequiredAuth")
+	}
+
+	for _, field := range c.CalloutAuth.Changes(remote.CalloutAuth) {
+		result = append(result, "CalloutAuth."+field)
+	}
+
+	return result
+}
+
+// Changes lists the fields of the remote callout auth which differ from this one
+func (a CalloutAuth) Changes(remote CalloutAuth) []string {
+	result := make([]string, 0)
+
+	if a.Domain != remote.Domain {
+		result = append(result, "Domain")
+	}
+
+	// Zuora may not return the password, only compare it when it does
+	if remote.Password != "" && a.Password != remote.Password {
+		result = append(result, "Password")
+	}
+
+	if a.Preemptive != remote.Preemptive {
+		result = append(result, "Preemptive")
+	}
+
+	if a.Username != remote.Username {
+		result = append(result, "Username")
+	}
+
+	return result
+}
+
+func calloutFromAPI(c zuora.Callout) Callout {
+	return Callout{
+		Active: c.Active,
+		CalloutAuth: CalloutAuth{
+			Domain:     c.CalloutAuth.Domain,
+			Password:   c.CalloutAuth.Password,
+			Preemptive: c.CalloutAuth.Preemptive,
+			Username:   c.CalloutAuth.Username,
+		},
+		CalloutBaseURL: c.CalloutBaseURL,
+		CalloutParams:  c.CalloutParams,
+		CalloutRetry:   c.CalloutRetry,
+		Description:    c.Description,
+		EventTypeName:  c.EventTypeName,
+		HTTPMethod:     c.HTTPMethod,
+		ID:             c.ID,
+		Name:           c.Name,
+		RequiredAuth:   c.RequiredAuth,
+	}
+}
+
+func (c Callout) toAPI() zuora.Callout {
+	return zuora.Callout{
+		Active: c.Active,
+		CalloutAuth: zuora.CalloutAuth{
+			Domain:     c.CalloutAuth.Domain,
+			Password:   c.CalloutAuth.Password,
+			Preemptive: c.CalloutAuth.Preemptive,
+			Username:   c.CalloutAuth.Username,
+		},
+		CalloutBaseURL: c.CalloutBaseURL,
+		CalloutParams:  c.CalloutParams,
+		CalloutRetry:   c.CalloutRetry,
+		Description:    c.Description,
+		EventTypeName:  c.EventTypeName,
+		HTTPMethod:     c.HTTPMethod,
+		ID:             c.ID,
+		Name:           c.Name,
+		RequiredAuth:   c.RequiredAuth,
+	}
 }
@@ -0,0 +1,265 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// managedProfileSuffix ends the description of the communication profiles
+// managed by ZNT, the profile names are chosen by the business
+const managedProfileSuffix = "(managed by znt)"
+
+// pendingProfilePrefix stands for the ID of a communication profile which is
+// created when the plan is applied
+const pendingProfilePrefix = "pending:"
+
+// CommunicationProfile is associated to each customer account, the
+// notifications are defined per profile
+type CommunicationProfile struct {
+	ID          string
+	Description string
+	Name        string
+}
+
+// managedProfileDescription returns the description of a managed profile
+func managedProfileDescription(description string) string {
+	return strings.TrimSpace(description + " " + managedProfileSuffix)
+}
+
+// Managed returns true when the communication profile is managed by ZNT
+func (p CommunicationProfile) Managed() bool {
+	return strings.HasSuffix(p.Description, managedProfileSuffix)
+}
+
+func (p CommunicationProfile) String() string {
+	if p.ID == "" {
+		return p.Name
+	}
+
+	return fmt.Sprintf("(%s) %s", p.ID, p.Name)
+}
+
+// NewCommunicationProfile declared in the template
+func NewCommunicationProfile(p CommunicationProfileTemplate) CommunicationProfile {
+	description := p.Description
+	if p.managed() {
+		description = managedProfileDescription(description)
+	}
+
+	return CommunicationProfile{Description: description, Name: p.Name}
+}
+
+func communicationProfileFromAPI(p zuora.CommunicationProfile) CommunicationProfile {
+	return CommunicationProfile{ID: p.ID, Description: p.Description, Name: p.ProfileName}
+}
+
+func (p CommunicationProfile) toAPI() zuora.CommunicationProfile {
+	return zuora.CommunicationProfile{ID: p.ID, Description: p.Description, ProfileName: p.Name}
+}
+
+// FetchCommunicationProfiles returns all communication profiles in the
+// associated Zuora tenant, sorted by name
+func FetchCommunicationProfiles(c *zuora.Client) ([]CommunicationProfile, error) {
+	profiles, err := c.QueryProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CommunicationProfile, 0, len(profiles))
+	for _, p := range profiles {
+		result = append(result, communicationProfileFromAPI(p))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Insert the communication profile in the targeted Zuora environment, returning its ID
+func (p CommunicationProfile) Insert(c *zuora.Client) (string, error) {
+	ID, err := c.CreateCommunicationProfile(p.toAPI())
+	if err != nil {
+		return "", fmt.Errorf("creating profile %s: %w", p, err)
+	}
+
+	return ID, nil
+}
+
+// Update the communication profile in place in the targeted Zuora environment
+func (p CommunicationProfile) Update(c *zuora.Client) error {
+	if err := c.UpdateCommunicationProfile(p.ID, p.toAPI()); err != nil {
+		return fmt.Errorf("updating profile %s: %w", p, err)
+	}
+
+	return nil
+}
+
+// Destroy the communication profile in the targeted Zuora environment
+func (p CommunicationProfile) Destroy(c *zuora.Client) error {
+	if err := c.DeleteCommunicationProfile(p.ID); err != nil {
+		return fmt.Errorf("deleting profile %s: %w", p, err)
+	}
+
+	return nil
+}
+
+// CommunicationProfileUpdate is a remote communication profile which differs
+// from the template, or is adopted by ZNT
+type CommunicationProfileUpdate struct {
+	Remote   CommunicationProfile
+	Template CommunicationProfile
+}
+
+func (u CommunicationProfileUpdate) String() string {
+	if !u.Remote.Managed() {
+		return u.Remote.String() + " (adopted)"
+	}
+
+	return fmt.Sprintf("%s (description: %q -> %q)", u.Remote, u.Remote.Description, u.Template.Description)
+}
+
+// CommunicationProfileDiff contains the differences between the template and
+// the remote environment. The profiles which are not managed by ZNT are only
+// created when missing, unless they are adopted. The managed profiles are only
+// deleted when declared with delete.
+type CommunicationProfileDiff struct {
+	Add    []CommunicationProfile
+	Remove []CommunicationProfile
+	Update []CommunicationProfileUpdate
+}
+
+// NewCommunicationProfileDiff compares the declared profiles to the remote ones
+func NewCommunicationProfileDiff(declared []CommunicationProfileTemplate, remote []CommunicationProfile) CommunicationProfileDiff {
+	result := CommunicationProfileDiff{}
+
+	remoteByName := make(map[string]CommunicationProfile, len(remote))
+	for _, p := range remote {
+		remoteByName[p.Name] = p
+	}
+
+	for _, d := range declared {
+		profile := NewCommunicationProfile(d)
+
+		rmt, ok := remoteByName[d.Name]
+		switch {
+		case d.Delete:
+			// pre-existing profiles are never removed, only the managed ones
+			if ok && rmt.Managed() {
+				result.Remove = append(result.Remove, rmt)
+			}
+
+		case !ok:
+			result.Add = append(result.Add, profile)
+
+		case !d.managed():
+			// created once, then left alone
+
+		case rmt.Managed() && rmt.Description != profile.Description, !rmt.Managed() && d.Adopt:
+			result.Update = append(result.Update, CommunicationProfileUpdate{Remote: rmt, Template: profile})
+		}
+	}
+
+	sort.Slice(result.Add, func(i, j int) bool { return result.Add[i].Name < result.Add[j].Name })
+	sort.Slice(result.Remove, func(i, j int) bool { return result.Remove[i].Name < result.Remove[j].Name })
+	sort.Slice(result.Update, func(i, j int) bool { return result.Update[i].Remote.Name < result.Update[j].Remote.Name })
+
+	return result
+}
+
+func (d CommunicationProfileDiff) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n--- Communication Profile Diff\n\n")
+
+	if len(d.Add) > 0 {
+		sb.WriteString("These profiles will be created: \n")
+		for _, p := range d.Add {
+			sb.WriteString("  * " + p.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Remove) > 0 {
+		sb.WriteString("These profiles will be deleted: \n")
+		for _, p := range d.Remove {
+			sb.WriteString("  * " + p.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Update) > 0 {
+		sb.WriteString("These profiles will be updated: \n")
+		for _, u := range d.Update {
+			sb.WriteString("  * " + u.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// pendingProfileID stands for the ID of a profile to create
+func pendingProfileID(name string) string {
+	return pendingProfilePrefix + name
+}
+
+// ProfileIDs returns the IDs of the remote profiles by name, along with the
+// pending IDs of the profiles the diff creates, and without the profiles it
+// deletes
+func (d CommunicationProfileDiff) ProfileIDs(remote map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(remote)+len(d.Add))
+	for name, IDs := range remote {
+		result[name] = IDs
+	}
+
+	for _, p := range d.Remove {
+		delete(result, p.Name)
+	}
+
+	for _, p := range d.Add {
+		result[p.Name] = []string{pendingProfileID(p.Name)}
+	}
+
+	return result
+}
+
+// ApplyAdd creates the missing profiles and updates the changed or adopted
+// ones, it returns the IDs of the created profiles by name
+func (d CommunicationProfileDiff) ApplyAdd(c *zuora.Client) (map[string]string, error) {
+	created := make(map[string]string)
+
+	for _, p := range d.Add {
+		ID, err := p.Insert(c)
+		if err != nil {
+			return created, err
+		}
+		created[p.Name] = ID
+	}
+
+	for _, u := range d.Update {
+		p := u.Template
+		p.ID = u.Remote.ID
+		if err := p.Update(c); err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+// ApplyRemove deletes the managed profiles declared with delete, it must run
+// once their notifications are deleted
+func (d CommunicationProfileDiff) ApplyRemove(c *zuora.Client) error {
+	for _, p := range d.Remove {
+		if err := p.Destroy(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
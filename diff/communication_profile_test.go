@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommunicationProfiles(t *testing.T) {
+	unmanaged := false
+
+	remote := []CommunicationProfile{
+		{ID: "1", Name: "Brand A", Description: "Brand A customers (managed by znt)"},
+		{ID: "2", Name: "Brand B", Description: "created in the UI"},
+		{ID: "3", Name: "Brand C", Description: "created in the UI"},
+		{ID: "4", Name: "Brand D", Description: "(managed by znt)"},
+		{ID: "5", Name: "Brand E", Description: "created in the UI"},
+	}
+
+	declared := []CommunicationProfileTemplate{
+		{Name: "Brand A", Description: "Brand A accounts"},
+		{Name: "Brand B", Delete: true},
+		{Name: "Brand C", Description: "Brand C accounts", Adopt: true},
+		{Name: "Brand D", Delete: true},
+		{Name: "Brand E", Managed: &unmanaged},
+		{Name: "Brand F", Description: "Brand F accounts"},
+		{Name: "Brand G", Delete: true},
+	}
+
+	t.Run("diffs the managed and adopted profiles", func(t *testing.T) {
+		got := NewCommunicationProfileDiff(declared, remote)
+
+		want := CommunicationProfileDiff{
+			Add: []CommunicationProfile{
+				{Name: "Brand F", Description: "Brand F accounts (managed by znt)"},
+			},
+			Remove: []CommunicationProfile{remote[3]},
+			Update: []CommunicationProfileUpdate{
+				{Remote: remote[0], Template: CommunicationProfile{Name: "Brand A", Description: "Brand A accounts (managed by znt)"}},
+				{Remote: remote[2], Template: CommunicationProfile{Name: "Brand C", Description: "Brand C accounts (managed by znt)"}},
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("keeps the managed profiles missing from the template", func(t *testing.T) {
+		got := NewCommunicationProfileDiff(nil, remote)
+
+		if !reflect.DeepEqual(got, CommunicationProfileDiff{}) {
+			t.Errorf("got %+v, want no change", got)
+		}
+	})
+
+	t.Run("notifies the profiles to create once they exist", func(t *testing.T) {
+		tpl := Template{
+			Profiles:              []string{"Brand *"},
+			CommunicationProfiles: declared,
+			Notifications: []NotificationTemplate{
+				{BaseObject: "Account", Triggers: []TriggerTemplate{{Name: "insert", Condition: "changeType == 'INSERT'"}}},
+			},
+		}
+
+		r := &Remote{CommunicationProfiles: remote, Profiles: profileIDsByName(remote)}
+
+		plan, err := NewPlan(&tpl, r, "https://example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var pending []int
+		for i, n := range plan.Notifications.Add {
+			if n.CommunicationProfileID == pendingProfileID("Brand F") {
+				pending = append(pending, i)
+			}
+		}
+
+		// Brand D is deleted, so no longer selected
+		if len(plan.Notifications.Add) != 5 || len(pending) != 1 {
+			t.Fatalf("got notifications %v", plan.Notifications.Add)
+		}
+
+		plan.Notifications.resolveProfiles(map[string]string{"Brand F": "6"})
+		if got := plan.Notifications.Add[pending[0]].CommunicationProfileID; got != "6" {
+			t.Errorf("got profile ID %q, want 6", got)
+		}
+	})
+}
@@ -0,0 +1,354 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+const managedEmailTemplateDescription = "email template managed by znt"
+
+// EmailTemplate is sent by the email notifications
+type EmailTemplate struct {
+	ID             string
+	Active         bool
+	Body           string
+	Description    string
+	EventTypeName  string
+	FromAddress    string
+	FromName       string
+	HTML           bool
+	Name           string
+	ReplyToAddress string
+	Subject        string
+	ToAddress      string
+	ToEmailType    string
+}
+
+// EmailTemplateName returns the name of the email template managed by ZNT
+func EmailTemplateName(name string) string {
+	return "znt-" + name
+}
+
+// NewEmailTemplate managed by ZNT
+func NewEmailTemplate(d EmailTemplateTemplate) EmailTemplate {
+	result := EmailTemplate{
+		Active:         true,
+		Body:           d.Body,
+		Description:    managedEmailTemplateDescription,
+		EventTypeName:  d.EventTypeName,
+		FromAddress:    d.From,
+		FromName:       d.FromName,
+		HTML:           d.HTML,
+		Name:           EmailTemplateName(d.Name),
+		ReplyToAddress: d.ReplyTo,
+		Subject:        d.Subject,
+		ToEmailType:    "BillToContact",
+	}
+
+	if strings.Contains(d.To, "@") {
+		result.ToEmailType = "SpecificEmails"
+		result.ToAddress = d.To
+	} else if d.To != "" {
+		result.ToEmailType = d.To
+	}
+
+	return result
+}
+
+// EmailTemplateDefinitions expected from the template, sorted by name
+func (t *Template) EmailTemplateDefinitions() []EmailTemplate {
+	result := make([]EmailTemplate, 0, len(t.EmailTemplates))
+	for _, d := range t.EmailTemplates {
+		result = append(result, NewEmailTemplate(d))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func (e EmailTemplate) String() string {
+	return fmt.Sprintf("%s on %s", e.Name, e.EventTypeName)
+}
+
+// Changes lists the managed fields of the remote email template which differ
+// from this email template
+func (e EmailTemplate) Changes(remote EmailTemplate) []string {
+	result := make([]string, 0)
+
+	// the template always expects the email template to be active
+	if !remote.Active {
+		result = append(result, "Active")
+	}
+
+	fields := []struct {
+		name           string
+		wanted, actual interface{}
+	}{
+		{"Body", e.Body, remote.Body},
+		{"Description", e.Description, remote.Description},
+		{"EventTypeName", e.EventTypeName, remote.EventTypeName},
+		{"FromAddress", e.FromAddress, remote.FromAddress},
+		{"FromName", e.FromName, remote.FromName},
+		{"HTML", e.HTML, remote.HTML},
+		{"ReplyToAddress", e.ReplyToAddress, remote.ReplyToAddress},
+		{"Subject", e.Subject, remote.Subject},
+		{"ToAddress", e.ToAddress, remote.ToAddress},
+		{"ToEmailType", e.ToEmailType, remote.ToEmailType},
+	}
+
+	for _, f := range fields {
+		if f.wanted != f.actual {
+			result = append(result, f.name)
+		}
+	}
+
+	return result
+}
+
+func emailTemplateFromAPI(e zuora.EmailTemplate) EmailTemplate {
+	return EmailTemplate{
+		ID:             e.ID,
+		Active:         e.Active,
+		Body:           e.EmailBody,
+		Description:    e.Description,
+		EventTypeName:  e.EventTypeName,
+		FromAddress:    e.FromEmailAddress,
+		FromName:       e.FromName,
+		HTML:           e.IsHTML,
+		Name:           e.Name,
+		ReplyToAddress: e.ReplyToEmailAddress,
+		Subject:        e.EmailSubject,
+		ToAddress:      e.ToEmailAddress,
+		ToEmailType:    e.ToEmailType,
+	}
+}
+
+func (e EmailTemplate) toAPI() zuora.EmailTemplate {
+	result := zuora.EmailTemplate{
+		ID:                  e.ID,
+		Active:              e.Active,
+		Description:         e.Description,
+		EmailBody:           e.Body,
+		EmailSubject:        e.Subject,
+		EventTypeName:       e.EventTypeName,
+		FromEmailAddress:    e.FromAddress,
+		FromEmailType:       "TenantEmail",
+		FromName:            e.FromName,
+		IsHTML:              e.HTML,
+		Name:                e.Name,
+		ReplyToEmailAddress: e.ReplyToAddress,
+		ReplyToEmailType:    "TenantEmail",
+		ToEmailAddress:      e.ToAddress,
+		ToEmailType:         e.ToEmailType,
+	}
+
+	if e.FromAddress != "" {
+		result.FromEmailType = "SpecificEmail"
+	}
+
+	if e.ReplyToAddress != "" {
+		result.ReplyToEmailType = "SpecificEmail"
+	}
+
+	return result
+}
+
+// FetchManagedEmailTemplates retrieves all managed email templates from Zuora
+func FetchManagedEmailTemplates(c *zuora.Client) ([]EmailTemplate, error) {
+	remote, err := c.ListEmailTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]EmailTemplate, 0)
+	for _, rmt := range remote {
+		if rmt.Description == managedEmailTemplateDescription {
+			result = append(result, emailTemplateFromAPI(rmt))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Insert the email template in the targeted Zuora environment, returning its ID
+func (e EmailTemplate) Insert(c *zuora.Client) (string, error) {
+	created, err := c.CreateEmailTemplate(e.toAPI())
+	if err != nil {
+		return "", fmt.Errorf("creating email template %s: %w", e, err)
+	}
+
+	return created.ID, nil
+}
+
+// Update the email template in place in the targeted Zuora environment
+func (e EmailTemplate) Update(c *zuora.Client) error {
+	if _, err := c.UpdateEmailTemplate(e.ID, e.toAPI()); err != nil {
+		return fmt.Errorf("updating email template %s: %w", e, err)
+	}
+
+	return nil
+}
+
+// Destroy the email template in the targeted Zuora environment
+func (e EmailTemplate) Destroy(c *zuora.Client) error {
+	if err := c.DeleteEmailTemplate(e.ID); err != nil {
+		return fmt.Errorf("deleting email template %s: %w", e, err)
+	}
+
+	return nil
+}
+
+// EmailTemplateUpdate is a remote email template which differs from the template
+type EmailTemplateUpdate struct {
+	Remote   EmailTemplate
+	Template EmailTemplate
+	Fields   []string
+}
+
+// Activation returns true when the only change is the email template reactivation
+func (u EmailTemplateUpdate) Activation() bool {
+	return len(u.Fields) == 1 && u.Fields[0] == "Active"
+}
+
+func (u EmailTemplateUpdate) String() string {
+	if u.Activation() {
+		return u.Remote.String() + " (activated)"
+	}
+
+	return u.Remote.String() + " (changed: " + strings.Join(u.Fields, ", ") + ")"
+}
+
+// EmailTemplateDiff contains the differences between the template and the remote environment
+type EmailTemplateDiff struct {
+	Add    []EmailTemplate
+	Remove []EmailTemplate
+	Update []EmailTemplateUpdate
+}
+
+// NewEmailTemplateDiff accepts email templates sorted by name and return the diff
+func NewEmailTemplateDiff(template, remote []EmailTemplate) EmailTemplateDiff {
+	result := EmailTemplateDiff{}
+
+	i := 0
+	j := 0
+
+	for i < len(template) && j < len(remote) {
+		if template[i].Name == remote[j].Name {
+			if fields := template[i].Changes(remote[j]); len(fields) > 0 {
+				result.Update = append(result.Update, EmailTemplateUpdate{
+					Remote:   remote[j],
+					Template: template[i],
+					Fields:   fields,
+				})
+			}
+			i++
+			j++
+		} else if template[i].Name < remote[j].Name {
+			result.Add = append(result.Add, template[i])
+			i++
+		} else {
+			result.Remove = append(result.Remove, remote[j])
+			j++
+		}
+	}
+
+	// remaining elements of a need to be added
+	for i < len(template) {
+		result.Add = append(result.Add, template[i])
+		i++
+	}
+
+	// remaining elements of remote need to be removed
+	for j < len(remote) {
+		result.Remove = append(result.Remove, remote[j])
+		j++
+	}
+
+	return result
+}
+
+func (d EmailTemplateDiff) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n--- Email Template Diff\n\n")
+
+	if len(d.Add) > 0 {
+		sb.WriteString("These email templates will be created: \n")
+		for _, e := range d.Add {
+			sb.WriteString("  * " + e.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Remove) > 0 {
+		sb.WriteString("These email templates will be deleted: \n")
+		for _, e := range d.Remove {
+			sb.WriteString("  * " + e.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Update) > 0 {
+		sb.WriteString("These email templates will be updated: \n")
+		for _, u := range d.Update {
+			sb.WriteString("  * " + u.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// ApplyAdd creates the missing email templates and updates the changed ones,
+// it returns the IDs of the created email templates by name. A failed
+// activation is returned as an ActivationError once the others are updated
+func (d EmailTemplateDiff) ApplyAdd(c *zuora.Client) (map[string]string, error) {
+	created := make(map[string]string)
+
+	for _, e := range d.Add {
+		id, err := e.Insert(c)
+		if err != nil {
+			return created, err
+		}
+		created[e.Name] = id
+	}
+
+	var activated activations
+	for _, u := range d.Update {
+		e := u.Template
+		e.ID = u.Remote.ID
+		err := e.Update(c)
+		if u.Template.Active && !u.Remote.Active {
+			activated.report("email template "+u.Remote.String(), err)
+			continue
+		}
+
+		if err != nil {
+			return created, err
+		}
+	}
+
+	return created, activated.err()
+}
+
+// ApplyRemove deletes the email templates no longer in the template, it must
+// run once the notifications no longer reference them
+func (d EmailTemplateDiff) ApplyRemove(c *zuora.Client) error {
+	for _, e := range d.Remove {
+		if err := e.Destroy(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
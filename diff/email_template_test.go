@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEmailTemplates(t *testing.T) {
+	t.Run("maps the template to managed email templates", func(t *testing.T) {
+		tpl := Template{
+			EmailTemplates: []EmailTemplateTemplate{
+				{Name: "welcome", EventTypeName: "znt-Account-onInsert", Subject: "Welcome", Body: "Hello", To: "billing@example.com", From: "noreply@example.com"},
+				{Name: "cancelled", EventTypeName: "znt-Subscription-onCancel", Subject: "Bye", Body: "Goodbye"},
+			},
+		}
+
+		got := tpl.EmailTemplateDefinitions()
+		want := []EmailTemplate{
+			{
+				Active:        true,
+				Body:          "Goodbye",
+				Description:   managedEmailTemplateDescription,
+				EventTypeName: "znt-Subscription-onCancel",
+				Name:          "znt-cancelled",
+				Subject:       "Bye",
+				ToEmailType:   "BillToContact",
+			},
+			{
+				Active:        true,
+				Body:          "Hello",
+				Description:   managedEmailTemplateDescription,
+				EventTypeName: "znt-Account-onInsert",
+				FromAddress:   "noreply@example.com",
+				Name:          "znt-welcome",
+				Subject:       "Welcome",
+				ToAddress:     "billing@example.com",
+				ToEmailType:   "SpecificEmails",
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+
+		api := got[1].toAPI()
+		if api.FromEmailType != "SpecificEmail" || api.ReplyToEmailType != "TenantEmail" {
+			t.Errorf("got from %q and reply-to %q email types", api.FromEmailType, api.ReplyToEmailType)
+		}
+	})
+
+	t.Run("diffs the email templates by name", func(t *testing.T) {
+		template := []EmailTemplate{
+			{Active: true, Name: "znt-a", Subject: "A"},
+			{Active: true, Name: "znt-b", Subject: "B"},
+			{Active: true, Name: "znt-c", Subject: "C"},
+		}
+		remote := []EmailTemplate{
+			{ID: "1", Active: false, Name: "znt-a", Subject: "A"},
+			{ID: "2", Active: true, Name: "znt-c", Subject: "changed"},
+			{ID: "3", Active: true, Name: "znt-d", Subject: "D"},
+		}
+
+		got := NewEmailTemplateDiff(template, remote)
+
+		if len(got.Add) != 1 || got.Add[0].Name != "znt-b" {
+			t.Errorf("got add %v", got.Add)
+		}
+
+		if len(got.Remove) != 1 || got.Remove[0].ID != "3" {
+			t.Errorf("got remove %v", got.Remove)
+		}
+
+		if len(got.Update) != 2 || !got.Update[0].Activation() || !reflect.DeepEqual(got.Update[1].Fields, []string{"Subject"}) {
+			t.Errorf("got update %v", got.Update)
+		}
+	})
+
+	t.Run("resolves the email templates of the notifications", func(t *testing.T) {
+		tpl := Template{
+			Profiles: []string{"Profile A"},
+			Notifications: []NotificationTemplate{
+				{
+					BaseObject:    "Account",
+					Triggers:      []TriggerTemplate{{Name: "insert", Condition: "changeType == 'INSERT'"}},
+					EmailTemplate: "welcome",
+				},
+			},
+			EmailTemplates: []EmailTemplateTemplate{
+				{Name: "welcome", EventTypeName: "znt-Account-onInsert", Subject: "Welcome", Body: "Hello"},
+			},
+		}
+
+		remote := &Remote{Profiles: map[string][]string{"Profile A": {"p1"}}}
+
+		plan, err := NewPlan(&tpl, remote, "https://example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := plan.Notifications.Add[0]
+		if !n.EmailActive || n.EmailTemplateName != "znt-welcome" || n.EmailTemplateID != "" {
+			t.Errorf("got pending notification %+v", n)
+		}
+
+		plan.Notifications.resolveEmailTemplates(map[string]string{"znt-welcome": "e1"})
+		if plan.Notifications.Add[0].EmailTemplateID != "e1" {
+			t.Errorf("got email template ID %q, want e1", plan.Notifications.Add[0].EmailTemplateID)
+		}
+
+		remote.EmailTemplates = []EmailTemplate{{ID: "e1", Name: "znt-welcome"}}
+		plan, err = NewPlan(&tpl, remote, "https://example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if plan.Notifications.Add[0].EmailTemplateID != "e1" {
+			t.Errorf("got email template ID %q, want e1", plan.Notifications.Add[0].EmailTemplateID)
+		}
+	})
+
+	t.Run("rejects an undeclared email template", func(t *testing.T) {
+		tpl := Template{
+			Profiles:   []string{"Profile A"},
+			EventTypes: []EventTypeTemplate{{Name: "order-shipped"}},
+			Notifications: []NotificationTemplate{
+				{EventType: "order-shipped", EmailTemplate: "missing"},
+			},
+		}
+
+		_, err := tpl.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}})
+		if want := `znt-order-shipped notification references undeclared email template "missing"`; err == nil || err.Error() != want {
+			t.Errorf("got %v want %q", err, want)
+		}
+	})
+
+	t.Run("reads the body file relative to the template", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, "welcome.html"), []byte("<p>Hello</p>"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		content := `
+callout:
+  calloutBaseurl: https://example.com/callout
+profiles:
+  - Profile A
+emailTemplates:
+  - name: welcome
+    eventTypeName: znt-Account-onInsert
+    subject: Welcome
+    bodyFile: welcome.html
+    html: true
+`
+		path := filepath.Join(dir, "template.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		tpl, err := ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := tpl.EmailTemplates[0].Body; got != "<p>Hello</p>" {
+			t.Errorf("got body %q", got)
+		}
+	})
+}
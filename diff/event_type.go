@@ -0,0 +1,310 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// CustomEventType is published by the tenant integrations, the notifications
+// can reference it instead of a trigger
+type CustomEventType struct {
+	ID          string
+	Active      bool
+	Description string
+	DisplayName string
+	Name        string
+	Namespace   string
+}
+
+// managedEventTypeSuffix ends the description of the custom event types
+// managed by ZNT, their description is chosen by the template
+const managedEventTypeSuffix = "(managed by znt)"
+
+// defaultEventTypeNamespace is the namespace of the custom event types
+// declared without one
+const defaultEventTypeNamespace = "user.notification"
+
+// CustomEventTypeName returns the name of the custom event type managed by ZNT
+func CustomEventTypeName(name string) string {
+	return "znt-" + name
+}
+
+// NewCustomEventType managed by ZNT
+func NewCustomEventType(e EventTypeTemplate) CustomEventType {
+	name := CustomEventTypeName(e.Name)
+
+	displayName := e.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+
+	return CustomEventType{
+		Active:      true,
+		Description: strings.TrimSpace(e.Description + " " + managedEventTypeSuffix),
+		DisplayName: displayName,
+		Name:        name,
+		Namespace:   e.Namespace,
+	}
+}
+
+// CustomEventTypes expected from the template, sorted by name
+func (t *Template) CustomEventTypes() []CustomEventType {
+	result := make([]CustomEventType, 0, len(t.EventTypes))
+	for _, e := range t.EventTypes {
+		result = append(result, NewCustomEventType(e))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func (e CustomEventType) String() string {
+	if e.Namespace == "" {
+		return e.Name
+	}
+
+	return e.Namespace + "." + e.Name
+}
+
+// Changes lists the fields of the remote custom event type which differ from
+// this custom event type
+func (e CustomEventType) Changes(remote CustomEventType) []string {
+	result := make([]string, 0)
+
+	// the template always expects the custom event type to be active
+	if !remote.Active {
+		result = append(result, "Active")
+	}
+
+	if e.Description != remote.Description {
+		result = append(result, "Description")
+	}
+
+	if e.DisplayName != remote.DisplayName {
+		result = append(result, "DisplayName")
+	}
+
+	if e.Namespace != remote.Namespace {
+		result = append(result, "Namespace")
+	}
+
+	return result
+}
+
+func customEventTypeFromAPI(e zuora.CustomEventType) CustomEventType {
+	return CustomEventType{
+		ID:          e.ID,
+		Active:      e.Active,
+		Description: e.Description,
+		DisplayName: e.DisplayName,
+		Name:        e.Name,
+		Namespace:   e.Namespace,
+	}
+}
+
+func (e CustomEventType) toAPI() zuora.CustomEventType {
+	return zuora.CustomEventType{
+		ID:          e.ID,
+		Active:      e.Active,
+		Description: e.Description,
+		DisplayName: e.DisplayName,
+		Name:        e.Name,
+		Namespace:   e.Namespace,
+	}
+}
+
+// FetchManagedCustomEventTypes retrieves all custom event types managed by
+// ZNT from Zuora, identified by their description suffix
+func FetchManagedCustomEventTypes(c *zuora.Client) ([]CustomEventType, error) {
+	remote, err := c.ListCustomEventTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CustomEventType, 0)
+	for _, rmt := range remote {
+		if strings.HasPrefix(rmt.Name, "znt-") && strings.HasSuffix(rmt.Description, managedEventTypeSuffix) {
+			result = append(result, customEventTypeFromAPI(rmt))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Insert the custom event type in the targeted Zuora environment
+func (e CustomEventType) Insert(c *zuora.Client) error {
+	if _, err := c.CreateCustomEventType(e.toAPI()); err != nil {
+		return fmt.Errorf("creating event type %s: %w", e, err)
+	}
+
+	return nil
+}
+
+// Update the custom event type in place in the targeted Zuora environment
+func (e CustomEventType) Update(c *zuora.Client) error {
+	if _, err := c.UpdateCustomEventType(e.ID, e.toAPI()); err != nil {
+		return fmt.Errorf("updating event type %s: %w", e, err)
+	}
+
+	return nil
+}
+
+// Destroy the custom event type in the targeted Zuora environment
+func (e CustomEventType) Destroy(c *zuora.Client) error {
+	if err := c.DeleteCustomEventType(e.ID); err != nil {
+		return fmt.Errorf("deleting event type %s: %w", e, err)
+	}
+
+	return nil
+}
+
+// CustomEventTypeUpdate is a remote custom event type which differs from the template
+type CustomEventTypeUpdate struct {
+	Remote   CustomEventType
+	Template CustomEventType
+	Fields   []string
+}
+
+// Activation returns true when the only change is the custom event type reactivation
+func (u CustomEventTypeUpdate) Activation() bool {
+	return len(u.Fields) == 1 && u.Fields[0] == "Active"
+}
+
+func (u CustomEventTypeUpdate) String() string {
+	if u.Activation() {
+		return u.Remote.String() + " (activated)"
+	}
+
+	return u.Remote.String() + " (changed: " + strings.Join(u.Fields, ", ") + ")"
+}
+
+// CustomEventTypeDiff contains the differences between the template and the remote environment
+type CustomEventTypeDiff struct {
+	Add    []CustomEventType
+	Remove []CustomEventType
+	Update []CustomEventTypeUpdate
+}
+
+// NewCustomEventTypeDiff accepts custom event types sorted by name and return the diff
+func NewCustomEventTypeDiff(template, remote []CustomEventType) CustomEventTypeDiff {
+	result := CustomEventTypeDiff{}
+
+	i := 0
+	j := 0
+
+	for i < len(template) && j < len(remote) {
+		if template[i].Name == remote[j].Name {
+			if fields := template[i].Changes(remote[j]); len(fields) > 0 {
+				result.Update = append(result.Update, CustomEventTypeUpdate{
+					Remote:   remote[j],
+					Template: template[i],
+					Fields:   fields,
+				})
+			}
+			i++
+			j++
+		} else if template[i].Name < remote[j].Name {
+			result.Add = append(result.Add, template[i])
+			i++
+		} else {
+			result.Remove = append(result.Remove, remote[j])
+			j++
+		}
+	}
+
+	// remaining elements of a need to be added
+	for i < len(template) {
+		result.Add = append(result.Add, template[i])
+		i++
+	}
+
+	// remaining elements of remote need to be removed
+	for j < len(remote) {
+		result.Remove = append(result.Remove, remote[j])
+		j++
+	}
+
+	return result
+}
+
+func (d CustomEventTypeDiff) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n--- Event Type Diff\n\n")
+
+	if len(d.Add) > 0 {
+		sb.WriteString("These event types will be created: \n")
+		for _, e := range d.Add {
+			sb.WriteString("  * " + e.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Remove) > 0 {
+		sb.WriteString("These event types will be deleted: \n")
+		for _, e := range d.Remove {
+			sb.WriteString("  * " + e.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Update) > 0 {
+		sb.WriteString("These event types will be updated: \n")
+		for _, u := range d.Update {
+			sb.WriteString("  * " + u.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// ApplyAdd creates the missing custom event types and updates the changed
+// ones, it must run before the notifications referencing them are created. A
+// failed activation is returned as an ActivationError once the others are updated
+func (d CustomEventTypeDiff) ApplyAdd(c *zuora.Client) error {
+	for _, e := range d.Add {
+		if err := e.Insert(c); err != nil {
+			return err
+		}
+	}
+
+	var activated activations
+	for _, u := range d.Update {
+		e := u.Template
+		e.ID = u.Remote.ID
+		err := e.Update(c)
+		if u.Template.Active && !u.Remote.Active {
+			activated.report("custom event type "+u.Remote.String(), err)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return activated.err()
+}
+
+// ApplyRemove deletes the custom event types no longer in the template, it
+// must run once the notifications no longer reference them
+func (d CustomEventTypeDiff) ApplyRemove(c *zuora.Client) error {
+	for _, e := range d.Remove {
+		if err := e.Destroy(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
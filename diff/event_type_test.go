@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCustomEventTypes(t *testing.T) {
+	tpl := Template{
+		Profiles: []string{"Profile A"},
+		EventTypes: []EventTypeTemplate{
+			{Name: "order-shipped", DisplayName: "Order shipped", Description: "Published by the warehouse", Namespace: "user.notification"},
+			{Name: "order-cancelled"},
+		},
+		Notifications: []NotificationTemplate{
+			{EventType: "order-shipped", CalloutParams: map[string]string{"orderId": "<DataSource.Order.Id>"}},
+		},
+	}
+
+	t.Run("maps the template to managed custom event types", func(t *testing.T) {
+		got := tpl.CustomEventTypes()
+		want := []CustomEventType{
+			{Active: true, Description: "(managed by znt)", DisplayName: "znt-order-cancelled", Name: "znt-order-cancelled"},
+			{Active: true, Description: "Published by the warehouse (managed by znt)", DisplayName: "Order shipped", Name: "znt-order-shipped", Namespace: "user.notification"},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("notifies on the custom event type", func(t *testing.T) {
+		got, err := tpl.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 1 || got[0].EventTypeName != "znt-order-shipped" || got[0].Callout.EventTypeName != "znt-order-shipped" {
+			t.Errorf("got %+v", got)
+		}
+
+		if len(tpl.Triggers()) != 0 {
+			t.Errorf("got triggers %v", tpl.Triggers())
+		}
+	})
+
+	t.Run("notifies on the namespace of the custom event type", func(t *testing.T) {
+		namespaced := tpl
+		namespaced.EventTypes = []EventTypeTemplate{{Name: "order-shipped", Namespace: "com.example.warehouse"}}
+
+		got, err := namespaced.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 1 || got[0].EventTypeNamespace != "com.example.warehouse" {
+			t.Fatalf("got %+v want the com.example.warehouse namespace", got)
+		}
+
+		remote := got[0]
+		remote.Active = true
+		remote.EventTypeNamespace = defaultEventTypeNamespace
+		if fields := got[0].Changes(remote); !reflect.DeepEqual(fields, []string{"EventTypeNamespace"}) {
+			t.Errorf("got changes %v want EventTypeNamespace", fields)
+		}
+
+		namespaced.EventTypes[0].Namespace = ""
+		if got, _ := namespaced.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}}); got[0].EventTypeNamespace != defaultEventTypeNamespace {
+			t.Errorf("got namespace %q want the default one", got[0].EventTypeNamespace)
+		}
+	})
+
+	t.Run("rejects an undeclared event type", func(t *testing.T) {
+		invalid := Template{
+			Profiles:      []string{"Profile A"},
+			Notifications: []NotificationTemplate{{EventType: "missing"}},
+		}
+
+		if _, err := invalid.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("rejects an event type with triggers", func(t *testing.T) {
+		invalid := tpl
+		invalid.Notifications = []NotificationTemplate{{
+			BaseObject: "Account",
+			EventType:  "order-shipped",
+			Triggers:   []TriggerTemplate{{Name: "insert", Condition: "changeType == 'INSERT'"}},
+		}}
+
+		if _, err := invalid.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("diffs the custom event types by name", func(t *testing.T) {
+		remote := []CustomEventType{
+			{ID: "1", Active: true, Description: "(managed by znt)", DisplayName: "znt-order-cancelled", Name: "znt-order-cancelled"},
+			{ID: "3", Active: true, Description: "(managed by znt)", Name: "znt-order-returned"},
+			{ID: "2", Active: true, Description: "(managed by znt)", DisplayName: "Shipped", Name: "znt-order-shipped", Namespace: "user.notification"},
+		}
+
+		got := NewCustomEventTypeDiff(tpl.CustomEventTypes(), remote)
+
+		if len(got.Add) != 0 {
+			t.Errorf("got add %v", got.Add)
+		}
+
+		if len(got.Remove) != 1 || got.Remove[0].ID != "3" {
+			t.Errorf("got remove %v", got.Remove)
+		}
+
+		if len(got.Update) != 1 || !reflect.DeepEqual(got.Update[0].Fields, []string{"Description", "DisplayName"}) {
+			t.Errorf("got update %v", got.Update)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder matches "${NAME}" and "${NAME:-default}", and "$${" which
+// escapes a literal "${"
+var placeholder = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_.]*)(:-([^}]*))?\}`)
+
+// Interpolate replaces the "${NAME}" and "${NAME:-default}" placeholders in
+// every string of the template with the value returned by lookup
+func (t *Template) Interpolate(lookup func(name string) (string, bool)) error {
+	missing := make(map[string]bool)
+
+	interpolateValue(reflect.ValueOf(t).Elem(), func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+			if match == "$${" {
+				return "${"
+			}
+
+			groups := placeholder.FindStringSubmatch(match)
+			if value, ok := lookup(groups[1]); ok {
+				return value
+			}
+
+			if groups[2] != "" {
+				return groups[3]
+			}
+
+			missing[groups[1]] = true
+			return match
+		})
+	})
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("template variables are not defined: %s", strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// interpolateValue applies replace to every string reachable from v
+func interpolateValue(v reflect.Value, replace func(string) string) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(replace(v.String()))
+		}
+
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			interpolateValue(v.Elem(), replace)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				interpolateValue(v.Field(i), replace)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			interpolateValue(v.Index(i), replace)
+		}
+
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// map values are not addressable, interpolate a copy
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			interpolateValue(value, replace)
+			v.SetMapIndex(key, value)
+		}
+	}
+}
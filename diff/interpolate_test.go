@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		values := map[string]string{
+			"CALLOUT_PASSWORD": "verysecret",
+			"callout.host":     "example.com",
+		}
+
+		value, ok := values[name]
+		return value, ok
+	}
+
+	t.Run("replaces the placeholders in every string", func(t *testing.T) {
+		tpl, err := Parse(strings.NewReader(`
+callout:
+  calloutAuth:
+    password: ${CALLOUT_PASSWORD}
+    username: ${CALLOUT_USERNAME:-janedoe}
+  calloutBaseurl: https://${callout.host}/callout
+profiles:
+  - ${PROFILE:-Profile A}
+notifications:
+  - baseObject: Account
+    calloutParams:
+      Literal: $${NOT_A_VARIABLE}
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tpl.Interpolate(lookup); err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{
+			tpl.Callout.CalloutAuth.Password,
+			tpl.Callout.CalloutAuth.Username,
+			tpl.Callout.CalloutBaseURL,
+			tpl.Profiles[0],
+			tpl.Notifications[0].CalloutParams["Literal"],
+		}
+
+		want := []string{
+			"verysecret",
+			"janedoe",
+			"https://example.com/callout",
+			"Profile A",
+			"${NOT_A_VARIABLE}",
+		}
+
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("got %q want %q", got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("lists the undefined variables", func(t *testing.T) {
+		tpl, err := Parse(strings.NewReader(`{"profiles": ["${B}", "${A}", "${B}"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = tpl.Interpolate(lookup)
+		if err == nil || !strings.HasSuffix(err.Error(), ": A, B") {
+			t.Errorf("got %v want A and B to be undefined", err)
+		}
+	})
+}
@@ -0,0 +1,295 @@
+package diff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// templateFile is one of the files merged into a template
+type templateFile struct {
+	path     string
+	template *Template
+	keys     map[string]bool
+}
+
+// loader reads the template files and their includes
+type loader struct {
+	files []templateFile
+	seen  map[string]bool
+}
+
+func newLoader() *loader {
+	return &loader{seen: make(map[string]bool)}
+}
+
+// templateExtension returns true for the template files of a directory
+func templateExtension(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadPath loads the template file, or all the template files of the directory
+func (l *loader) loadPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return l.loadFile(path)
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && templateExtension(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return fmt.Errorf("%s: no JSON or YAML template in directory", path)
+	}
+
+	for _, name := range names {
+		if err := l.loadFile(filepath.Join(path, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *loader) loadFile(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	isJSON := looksLikeJSON(content)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		isJSON = true
+	case ".yaml", ".yml":
+		isJSON = false
+	}
+
+	return l.loadContent(path, filepath.Dir(path), content, isJSON)
+}
+
+// loadContent parses the template content, then loads its includes relative to dir
+func (l *loader) loadContent(path, dir string, content []byte, isJSON bool) error {
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		if l.seen[abs] {
+			return fmt.Errorf("%s: included more than once", path)
+		}
+		l.seen[abs] = true
+	}
+
+	template, keys, err := parse(path, content, isJSON)
+	if err != nil {
+		return err
+	}
+
+	for i := range template.EmailTemplates {
+		template.EmailTemplates[i].bodyDir = dir
+	}
+
+	l.files = append(l.files, templateFile{path, template, keys})
+
+	for _, include := range template.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+
+		if err := l.loadPath(include); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// merge the loaded files into a single template: the notifications and the
+// other resources are concatenated, the callout and the profiles must be
+// defined by one file
+func (l *loader) merge() (*Template, error) {
+	if len(l.files) == 1 {
+		result := *l.files[0].template
+		result.Include = nil
+		if err := readBodyFiles(&result); err != nil {
+			return nil, err
+		}
+		return &result, checkDuplicates(&result)
+	}
+
+	result := &Template{}
+	var calloutFile, profilesFile string
+
+	for _, f := range l.files {
+		if f.keys["callout"] {
+			if calloutFile != "" {
+				return nil, fmt.Errorf("callout is defined in both %s and %s", calloutFile, f.path)
+			}
+			calloutFile = f.path
+			result.Callout = f.template.Callout
+		}
+
+		if f.keys["profiles"] {
+			if profilesFile != "" {
+				return nil, fmt.Errorf("profiles are defined in both %s and %s", profilesFile, f.path)
+			}
+			profilesFile = f.path
+			result.Profiles = f.template.Profiles
+		}
+
+		result.Notifications = append(result.Notifications, f.template.Notifications...)
+		result.EmailTemplates = append(result.EmailTemplates, f.template.EmailTemplates...)
+		result.EventTypes = append(result.EventTypes, f.template.EventTypes...)
+		result.ScheduledEvents = append(result.ScheduledEvents, f.template.ScheduledEvents...)
+		result.CommunicationProfiles = append(result.CommunicationProfiles, f.template.CommunicationProfiles...)
+	}
+
+	if calloutFile == "" {
+		return nil, fmt.Errorf("callout is not defined by any of the %d template files", len(l.files))
+	}
+
+	if profilesFile == "" {
+		return nil, fmt.Errorf("profiles are not defined by any of the %d template files", len(l.files))
+	}
+
+	if err := readBodyFiles(result); err != nil {
+		return nil, err
+	}
+
+	return result, checkDuplicates(result)
+}
+
+// readBodyFiles replaces the body of the email templates with the content of
+// their body file, relative to the file declaring it
+func readBodyFiles(t *Template) error {
+	for i, e := range t.EmailTemplates {
+		if e.BodyFile == "" {
+			continue
+		}
+
+		if e.Body != "" {
+			return fmt.Errorf("email template %s has both a body and a body file", e.Name)
+		}
+
+		path := e.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(e.bodyDir, path)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("email template %s: %w", e.Name, err)
+		}
+
+		t.EmailTemplates[i].Body = string(content)
+	}
+
+	return nil
+}
+
+// checkDuplicates returns an error when two triggers have the same event type
+// name, two custom event types, scheduled events, email templates or
+// communication profiles the same name, or when a trigger, a custom event type
+// and a scheduled event share their Zuora event type name
+func checkDuplicates(t *Template) error {
+	if err := checkDuplicateTriggers(t); err != nil {
+		return err
+	}
+
+	kinds := make(map[string]string)
+	for _, trigger := range t.Triggers() {
+		kinds[trigger.EventType.Name] = "trigger"
+	}
+
+	for _, e := range t.EventTypes {
+		name := CustomEventTypeName(e.Name)
+		if kind, ok := kinds[name]; ok {
+			if kind == "event type" {
+				return fmt.Errorf("event type %s is defined more than once", e.Name)
+			}
+			return fmt.Errorf("event type %s has the same name as a %s", name, kind)
+		}
+		kinds[name] = "event type"
+	}
+
+	for _, s := range t.ScheduledEvents {
+		name := ScheduledEventName(s.BaseObject, s.Name)
+		if kind, ok := kinds[name]; ok {
+			if kind == "scheduled event" {
+				return fmt.Errorf("scheduled event %s is defined more than once", name)
+			}
+			return fmt.Errorf("scheduled event %s has the same name as a %s", name, kind)
+		}
+		kinds[name] = "scheduled event"
+	}
+
+	sources := make(map[string]string)
+	for _, e := range t.EmailTemplates {
+		source := e.source
+		if source == "" {
+			source = "template"
+		}
+
+		if previous, ok := sources[e.Name]; ok {
+			return fmt.Errorf("email template %s is defined in both %s and %s", e.Name, previous, source)
+		}
+		sources[e.Name] = source
+	}
+
+	profiles := make(map[string]bool)
+	for _, p := range t.CommunicationProfiles {
+		if profiles[p.Name] {
+			return fmt.Errorf("communication profile %s is defined more than once", p.Name)
+		}
+		profiles[p.Name] = true
+	}
+
+	return nil
+}
+
+// checkDuplicateTriggers returns an error naming the source files when two
+// triggers have the same event type name
+func checkDuplicateTriggers(t *Template) error {
+	sources := make(map[string]string)
+
+	for _, n := range t.Notifications {
+		for _, trigger := range n.Triggers {
+			name := NewTrigger(n.BaseObject, trigger.Name, trigger.Condition).EventType.Name
+			source := n.source
+			if source == "" {
+				source = "template"
+			}
+
+			if previous, ok := sources[name]; ok {
+				return fmt.Errorf("trigger %s is defined in both %s and %s", name, previous, source)
+			}
+			sources[name] = source
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	write := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+
+		for name, content := range files {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	shared := `
+callout:
+  calloutBaseurl: https://example.com/callout
+profiles:
+  - Profile A
+`
+
+	account := `{"notifications": [{"baseObject": "Account", "triggers": [{"name": "insert", "condition": "changeType == 'INSERT'"}]}]}`
+
+	subscription := `
+notifications:
+  - baseObject: Subscription
+    triggers:
+      - name: insert
+        condition: changeType == 'INSERT'
+`
+
+	t.Run("merges the templates of a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"shared.yaml":      shared,
+			"account.json":     account,
+			"subscription.yml": subscription,
+			"README.md":        "not a template",
+		})
+
+		tpl, err := ParseFile(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tpl.Callout.CalloutBaseURL != "https://example.com/callout" || len(tpl.Profiles) != 1 {
+			t.Errorf("got callout %v and profiles %v", tpl.Callout, tpl.Profiles)
+		}
+
+		if len(tpl.Notifications) != 2 || tpl.Notifications[0].BaseObject != "Account" || tpl.Notifications[1].BaseObject != "Subscription" {
+			t.Errorf("got notifications %v", tpl.Notifications)
+		}
+	})
+
+	t.Run("merges the included templates", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"template.yaml":     "include:\n  - account.json\n  - subscription.yaml\n" + shared,
+			"account.json":      account,
+			"subscription.yaml": subscription,
+		})
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tpl.Notifications) != 2 || tpl.Include != nil {
+			t.Errorf("got notifications %v and include %v", tpl.Notifications, tpl.Include)
+		}
+	})
+
+	t.Run("rejects the duplicate triggers naming their files", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"a.yaml": shared,
+			"b.json": account,
+			"c.json": account,
+		})
+
+		_, err := ParseFile(dir)
+		if err == nil || !strings.Contains(err.Error(), "b.json") || !strings.Contains(err.Error(), "c.json") {
+			t.Errorf("got %v want an error naming b.json and c.json", err)
+		}
+	})
+
+	t.Run("rejects a callout defined twice", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"a.yaml": shared,
+			"b.yaml": shared,
+		})
+
+		_, err := ParseFile(dir)
+		if err == nil || !strings.Contains(err.Error(), "callout is defined in both") {
+			t.Errorf("got %v want a callout defined twice error", err)
+		}
+	})
+
+	t.Run("rejects an event type named like a scheduled event", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"a.yaml": shared,
+			"b.yaml": `
+eventTypes:
+  - name: Invoice-due
+scheduledEvents:
+  - name: due
+    baseObject: Invoice
+    field: DueDate
+    condition: Balance > 0
+`,
+		})
+
+		_, err := ParseFile(dir)
+		if err == nil || !strings.Contains(err.Error(), "znt-Invoice-due") {
+			t.Errorf("got %v want a shared event type name error", err)
+		}
+	})
+
+	t.Run("merges the communication profiles", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"a.yaml": shared,
+			"b.yaml": "communicationProfiles:\n  - name: Brand A\n",
+			"c.yaml": "communicationProfiles:\n  - name: Brand B\n",
+		})
+
+		tpl, err := ParseFile(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tpl.CommunicationProfiles) != 2 || tpl.CommunicationProfiles[1].Name != "Brand B" {
+			t.Errorf("got communication profiles %v", tpl.CommunicationProfiles)
+		}
+	})
+
+	t.Run("rejects a communication profile declared twice", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, map[string]string{
+			"a.yaml": shared,
+			"b.yaml": "communicationProfiles:\n  - name: Brand A\n",
+			"c.yaml": "communicationProfiles:\n  - name: Brand A\n",
+		})
+
+		_, err := ParseFile(dir)
+		if err == nil || !strings.Contains(err.Error(), "Brand A") {
+			t.Errorf("got %v want a duplicate communication profile error", err)
+		}
+	})
+}
@@ -2,26 +2,41 @@ package diff
 
 import (
 	"fmt"
-	"log"
+	"reflect"
+	"sort"
 	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
 )
 
 const managedNotificationDescription = "notification managed by znt"
 
-// Notification fires a callout when the associated event is triggered
+// Notification fires a callout and/or sends an email when the associated
+// event is triggered
 type Notification struct {
 	Active                 bool
 	Callout                Callout
 	CalloutActive          bool
 	CommunicationProfileID string
 	Description            string
+	EmailActive            bool
+	EmailTemplateID        string
 	EventTypeName          string
+	FilterRuleParams       map[string]string
 	ID                     string
 	Name                   string
+
+	// EventTypeNamespace is the namespace of the custom event type, it is
+	// empty for the triggers and the scheduled events
+	EventTypeNamespace string
+
+	// EmailTemplateName is the managed email template sent by the
+	// notification, its ID is resolved once the email template exists
+	EmailTemplateName string
 }
 
 // NotificationDefinitions expected from the template
-func (t *Template) NotificationDefinitions(profileIDByName map[string]string) []Notification {
+func (t *Template) NotificationDefinitions(profileIDsByName map[string][]string) ([]Notification, error) {
 	result := make([]Notification, 0)
 
 	baseCallout := t.Callout
@@ -31,40 +46,213 @@ func (t *Template) NotificationDefinitions(profileIDByName map[string]string) []
 	baseCallout.HTTPMethod = "POST"
 	baseCallout.RequiredAuth = true
 
-	profilesIDs := make([]string, 0)
-	for _, profileName := range t.Profiles {
-		if profileID, ok := profileIDByName[profileName]; ok {
-			profilesIDs = append(profilesIDs, profileID)
-		} else {
-			log.Fatalf("profile %q not found in Zuora environment", profileName)
+	declared := make(map[string]bool)
+	for _, e := range t.EmailTemplates {
+		declared[e.Name] = true
+	}
+
+	declaredEvents := make(map[string]bool)
+	namespaces := make(map[string]string)
+	for _, e := range t.EventTypes {
+		declaredEvents[CustomEventTypeName(e.Name)] = true
+
+		namespaces[CustomEventTypeName(e.Name)] = e.Namespace
+		if e.Namespace == "" {
+			namespaces[CustomEventTypeName(e.Name)] = defaultEventTypeNamespace
 		}
 	}
+	for _, s := range t.ScheduledEvents {
+		declaredEvents[ScheduledEventName(s.BaseObject, s.Name)] = true
+	}
 
 	for _, n := range t.Notifications {
-		for _, t := range n.Triggers {
-			for _, pID := range profilesIDs {
-				trigger := NewTrigger(n.BaseObject, t.Name, t.Condition)
+		profilesIDs, err := n.profileIDs(t.Profiles, profileIDsByName)
+		if err != nil {
+			return nil, err
+		}
 
-				callout := baseCallout
+		var emailTemplateName string
+		if n.EmailTemplate != "" {
+			if n.EmailTemplateID != "" {
+				return nil, fmt.Errorf("%s notification has both an email template and an email template ID", n.label())
+			}
+
+			if !declared[n.EmailTemplate] {
+				return nil, fmt.Errorf("%s notification references undeclared email template %q", n.label(), n.EmailTemplate)
+			}
+
+			emailTemplateName = EmailTemplateName(n.EmailTemplate)
+		}
 
-				callout.CalloutParams = n.CalloutParams
-				callout.EventTypeName = trigger.EventType.Name
-				callout.Name = trigger.EventType.Name
+		events, err := n.events(declaredEvents)
+		if err != nil {
+			return nil, err
+		}
 
-				result = append(result, Notification{
+		for _, e := range events {
+			for _, pID := range profilesIDs {
+				notification := Notification{
 					Active:                 true,
-					Callout:                callout,
-					CalloutActive:          true,
+					CalloutActive:          n.calloutActive(),
 					CommunicationProfileID: pID,
 					Description:            managedNotificationDescription,
-					EventTypeName:          trigger.EventType.Name,
-					Name:                   trigger.EventType.Name,
-				})
+					EmailActive:            n.emailActive(),
+					EmailTemplateID:        n.EmailTemplateID,
+					EmailTemplateName:      emailTemplateName,
+					EventTypeName:          e.name,
+					EventTypeNamespace:     namespaces[e.name],
+					FilterRuleParams:       n.ScheduledEventParams,
+					Name:                   e.name,
+				}
+
+				if notification.CalloutActive {
+					// the trigger override wins over the notification one
+					callout := n.Callout.merge(e.callout).apply(baseCallout)
+
+					callout.CalloutParams = n.CalloutParams
+					callout.EventTypeName = e.name
+					callout.Name = e.name
+
+					notification.Callout = callout
+				}
+
+				result = append(result, notification)
 			}
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// notificationEvent is an event type fired for a notification, along with
+// the callout override of its trigger
+type notificationEvent struct {
+	name    string
+	callout *CalloutOverride
+}
+
+// events returns the event types of the notification: either its custom
+// event type, its scheduled event, or the event types of its triggers
+func (n NotificationTemplate) events(declaredEvents map[string]bool) ([]notificationEvent, error) {
+	var name, kind string
+	switch {
+	case n.EventType != "" && n.ScheduledEvent != "":
+		return nil, fmt.Errorf("notification on event type %q cannot have a scheduled event", n.EventType)
+	case n.EventType != "":
+		name, kind = CustomEventTypeName(n.EventType), "event type"
+	case n.ScheduledEvent != "":
+		name, kind = ScheduledEventName(n.BaseObject, n.ScheduledEvent), "scheduled event"
+	default:
+		result := make([]notificationEvent, 0, len(n.Triggers))
+		for _, t := range n.Triggers {
+			trigger := NewTrigger(n.BaseObject, t.Name, t.Condition)
+			result = append(result, notificationEvent{trigger.EventType.Name, t.Callout})
+		}
+
+		return result, nil
+	}
+
+	if len(n.Triggers) > 0 {
+		return nil, fmt.Errorf("notification on %s %s cannot have triggers", kind, name)
+	}
+
+	if !declaredEvents[name] {
+		return nil, fmt.Errorf("notification references undeclared %s %s", kind, name)
+	}
+
+	return []notificationEvent{{name: name}}, nil
+}
+
+// calloutActive returns false when the notification only sends an email
+func (n NotificationTemplate) calloutActive() bool {
+	return n.CalloutActive == nil || *n.CalloutActive
+}
+
+// emailActive returns true when the notification has an active email template
+func (n NotificationTemplate) emailActive() bool {
+	return (n.EmailTemplateID != "" || n.EmailTemplate != "") && (n.EmailActive == nil || *n.EmailActive)
+}
+
+// resolveEmailTemplates sets the ID of the managed email templates sent by
+// the notifications, the email templates still to create are left unresolved
+func resolveEmailTemplates(notifications []Notification, idByName map[string]string) {
+	for i, n := range notifications {
+		if id, ok := idByName[n.EmailTemplateName]; ok && n.EmailTemplateName != "" {
+			notifications[i].EmailTemplateID = id
+		}
+	}
+}
+
+// label identifies the notification in the messages
+func (n NotificationTemplate) label() string {
+	switch {
+	case n.EventType != "":
+		return CustomEventTypeName(n.EventType)
+	case n.ScheduledEvent != "":
+		return ScheduledEventName(n.BaseObject, n.ScheduledEvent)
+	default:
+		return n.BaseObject
+	}
+}
+
+// profileIDs returns the IDs of the profiles selected by the notification,
+// the top-level profiles by default
+func (n NotificationTemplate) profileIDs(defaultProfiles []string, profileIDsByName map[string][]string) ([]string, error) {
+	profiles, err := n.profiles(defaultProfiles, profileIDsByName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		result = append(result, p.ID)
+	}
+
+	return result, nil
+}
+
+// profiles resolves the profile rules of the notification, the top-level
+// profiles by default, without the excluded profiles
+func (n NotificationTemplate) profiles(defaultProfiles []string, profileIDsByName map[string][]string) ([]ResolvedProfile, error) {
+	rules := defaultProfiles
+	if len(n.Profiles) > 0 {
+		rules = n.Profiles
+	}
+
+	selected, err := ResolveProfiles(rules, profileIDsByName)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded, err := ResolveProfiles(n.ExcludeProfiles, profileIDsByName)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedBy := make(map[string]string)
+	for _, p := range excluded {
+		excludedBy[p.ID] = p.Rule
+	}
+
+	// every exclusion rule must remove at least one selected profile
+	used := make(map[string]bool)
+	result := make([]ResolvedProfile, 0, len(selected))
+	for _, p := range selected {
+		if rule, ok := excludedBy[p.ID]; ok {
+			used[rule] = true
+			continue
+		}
+
+		result = append(result, p)
+	}
+
+	for _, rule := range n.ExcludeProfiles {
+		if !used[rule] {
+			return nil, fmt.Errorf("%s notification excludes profile %q which is not selected", n.label(), rule)
+		}
+	}
+
+	return result, nil
 }
 
 // Equals verify that two notification have the same com. profile ID and event type name
@@ -77,23 +265,111 @@ func (n Notification) LessThan(another Notification) bool {
 	return n.CommunicationProfileID < another.CommunicationProfileID || n.CommunicationProfileID == another.CommunicationProfileID && n.EventTypeName < another.EventTypeName
 }
 
+// Changes lists the managed fields of the remote notification which differ
+// from the template notification
+func (n Notification) Changes(remote Notification) []string {
+	result := make([]string, 0)
+
+	// the template always expects the notification to be active
+	if !remote.Active {
+		result = append(result, "Active")
+	}
+
+	if n.CalloutActive != remote.CalloutActive {
+		result = append(result, "CalloutActive")
+	}
+
+	if n.Description != remote.Description {
+		result = append(result, "Description")
+	}
+
+	if n.EmailActive != remote.EmailActive {
+		result = append(result, "EmailActive")
+	}
+
+	if n.EmailTemplateID != remote.EmailTemplateID {
+		result = append(result, "EmailTemplateID")
+	}
+
+	if n.EventTypeNamespace != "" && n.EventTypeNamespace != remote.EventTypeNamespace {
+		result = append(result, "EventTypeNamespace")
+	}
+
+	if (len(n.FilterRuleParams) > 0 || len(remote.FilterRuleParams) > 0) && !reflect.DeepEqual(n.FilterRuleParams, remote.FilterRuleParams) {
+		result = append(result, "FilterRuleParams")
+	}
+
+	if n.Name != remote.Name {
+		result = append(result, "Name")
+	}
+
+	// the callout of an email notification is not sent
+	if n.CalloutActive {
+		for _, field := range n.Callout.Changes(remote.Callout) {
+			result = append(result, "Callout."+field)
+		}
+	}
+
+	return result
+}
+
+// NotificationUpdate is a remote notification which differs from the template
+type NotificationUpdate struct {
+	Remote   Notification
+	Template Notification
+	Fields   []string
+}
+
+// Activation returns true when the only change is the notification reactivation
+func (u NotificationUpdate) Activation() bool {
+	return len(u.Fields) == 1 && u.Fields[0] == "Active"
+}
+
+func (u NotificationUpdate) String() string {
+	if u.Activation() {
+		return u.Remote.String() + " (activated)"
+	}
+
+	return u.Remote.String() + " (changed: " + strings.Join(u.Fields, ", ") + ")"
+}
+
 // NotificationDiff contains the differences between the template and the remote environment
 type NotificationDiff struct {
 	Add    []Notification
 	Remove []Notification
-	Update []Notification
+	Update []NotificationUpdate
+}
+
+func sortNotifications(notifications []Notification) []Notification {
+	result := make([]Notification, len(notifications))
+	copy(result, notifications)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].LessThan(result[j])
+	})
+
+	return result
 }
 
-// NewNotificationDiff accepts sorted trigger arrays and return the diff
+// NewNotificationDiff compares the template and remote notifications and return the diff
 func NewNotificationDiff(template, remote []Notification) NotificationDiff {
 	result := NotificationDiff{}
 
+	template = sortNotifications(template)
+	remote = sortNotifications(remote)
+
 	i := 0
 	j := 0
 
 	for i < len(template) && j < len(remote) {
 		if template[i].Equals(remote[j]) {
-			result.Update = append(result.Update, remote[j])
+			if fields := template[i].Changes(remote[j]); len(fields) > 0 {
+				result.Update = append(result.Update, NotificationUpdate{
+					Remote:   remote[j],
+					Template: template[i],
+					Fields:   fields,
+				})
+			}
 			i++
 			j++
 		} else if template[i].LessThan(remote[j]) {
@@ -117,16 +393,6 @@ func NewNotificationDiff(template, remote []Notification) NotificationDiff {
 		j++
 	}
 
-	// TODO filter out the elements in update which already match with the template
-	tmp := make([]Notification, 0)
-	for _, needUpdate := range result.Update {
-		if !needUpdate.Active {
-			tmp = append(tmp, needUpdate)
-		}
-
-	}
-	result.Update = tmp
-
 	return result
 }
 
@@ -158,10 +424,152 @@ func (d NotificationDiff) String() string {
 	if len(d.Update) > 0 {
 		sb.WriteString("These notifications will be updated: \n")
 		for _, t := range d.Update {
-			sb.WriteString("  * " + t.String() + " (activated)\n")
+			sb.WriteString("  * " + t.String() + "\n")
 		}
 		sb.WriteString("\n")
 	}
 
 	return sb.String()
 }
+
+func notificationFromAPI(n zuora.NotificationDefinition) Notification {
+	result := Notification{
+		Active:                 n.Active,
+		CalloutActive:          n.CalloutActive,
+		CommunicationProfileID: n.CommunicationProfileID,
+		Description:            n.Description,
+		EmailActive:            n.EmailActive,
+		EmailTemplateID:        n.EmailTemplateID,
+		EventTypeName:          n.EventTypeName,
+		EventTypeNamespace:     n.EventTypeNamespace,
+		FilterRuleParams:       n.FilterRuleParams,
+		ID:                     n.ID,
+		Name:                   n.Name,
+	}
+
+	if n.Callout != nil {
+		result.Callout = calloutFromAPI(*n.Callout)
+	}
+
+	return result
+}
+
+func (n Notification) toAPI() zuora.NotificationDefinition {
+	result := zuora.NotificationDefinition{
+		Active:                 n.Active,
+		CalloutActive:          n.CalloutActive,
+		CommunicationProfileID: n.CommunicationProfileID,
+		Description:            n.Description,
+		EmailActive:            n.EmailActive,
+		EmailTemplateID:        n.EmailTemplateID,
+		EventTypeName:          n.EventTypeName,
+		EventTypeNamespace:     n.EventTypeNamespace,
+		FilterRuleParams:       n.FilterRuleParams,
+		ID:                     n.ID,
+		Name:                   n.Name,
+	}
+
+	// email notifications are sent without callout
+	if n.CalloutActive {
+		callout := n.Callout.toAPI()
+		result.Callout = &callout
+	}
+
+	return result
+}
+
+// Insert the notification definition in the target Zuora environment
+func (n Notification) Insert(c *zuora.Client) error {
+	if _, err := c.CreateNotificationDefinition(n.toAPI()); err != nil {
+		return fmt.Errorf("creating notification %s: %w", n, err)
+	}
+
+	return nil
+}
+
+// Update the notification definition in place in the targeted Zuora environment
+func (n Notification) Update(c *zuora.Client) error {
+	if _, err := c.UpdateNotificationDefinition(n.ID, n.toAPI()); err != nil {
+		return fmt.Errorf("updating notification %s: %w", n, err)
+	}
+
+	return nil
+}
+
+// Destroy the notification definition in the targeted Zuora environment
+func (n Notification) Destroy(c *zuora.Client) error {
+	if err := c.DeleteNotificationDefinition(n.ID); err != nil {
+		return fmt.Errorf("deleting notification %s: %w", n, err)
+	}
+
+	return nil
+}
+
+// resolveEmailTemplates sets the ID of the email templates created while
+// applying the plan
+func (d NotificationDiff) resolveEmailTemplates(idByName map[string]string) {
+	resolveEmailTemplates(d.Add, idByName)
+	for i := range d.Update {
+		n := &d.Update[i].Template
+		if id, ok := idByName[n.EmailTemplateName]; ok && n.EmailTemplateName != "" {
+			n.EmailTemplateID = id
+		}
+	}
+}
+
+// resolveProfiles replaces the pending IDs of the profiles created while
+// applying the plan
+func (d NotificationDiff) resolveProfiles(IDByName map[string]string) {
+	for i, n := range d.Add {
+		if name := strings.TrimPrefix(n.CommunicationProfileID, pendingProfilePrefix); name != n.CommunicationProfileID {
+			d.Add[i].CommunicationProfileID = IDByName[name]
+		}
+	}
+}
+
+// ApplyRemove deletes the notifications no longer in the template, it must
+// run before the associated triggers are destroyed
+func (d NotificationDiff) ApplyRemove(c *zuora.Client) error {
+	for _, n := range d.Remove {
+		if err := n.Destroy(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyAdd creates the notifications missing from the targeted Zuora
+// environment, it must run after the associated triggers are created
+func (d NotificationDiff) ApplyAdd(c *zuora.Client) error {
+	for _, n := range d.Add {
+		if err := n.Insert(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyUpdate replaces the changed notifications with their template
+// definition, the inactive notifications are reactivated and a failed
+// activation is returned as an ActivationError once the others are updated
+func (d NotificationDiff) ApplyUpdate(c *zuora.Client) error {
+	var activated activations
+	for _, u := range d.Update {
+		n := u.Template
+		n.ID = u.Remote.ID
+		n.Callout.ID = u.Remote.Callout.ID
+		err := n.Update(c)
+		if u.Template.Active && !u.Remote.Active {
+			activated.report("notification "+u.Remote.String(), err)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return activated.err()
+}
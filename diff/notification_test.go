@@ -7,9 +7,9 @@ import (
 )
 
 func TestNotifications(t *testing.T) {
-	profiles := map[string]string{
-		"Profile A": "123456789",
-		"Profile B": "987654321",
+	profiles := map[string][]string{
+		"Profile A": {"123456789"},
+		"Profile B": {"987654321"},
 	}
 
 	names := []string{
@@ -53,7 +53,10 @@ func TestNotifications(t *testing.T) {
 			t.Error(err)
 		}
 
-		got := tpl.NotificationDefinitions(profiles)
+		got, err := tpl.NotificationDefinitions(profiles)
+		if err != nil {
+			t.Error(err)
+		}
 
 		want := []Notification{
 			{
@@ -78,7 +81,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[0],
 				Name:                   names[0],
@@ -125,7 +128,10 @@ func TestNotifications(t *testing.T) {
 			t.Error(err)
 		}
 
-		got := tpl.NotificationDefinitions(profiles)
+		got, err := tpl.NotificationDefinitions(profiles)
+		if err != nil {
+			t.Error(err)
+		}
 
 		want := []Notification{
 			{
@@ -150,7 +156,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[0],
 				Name:                   names[0],
@@ -178,7 +184,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile B"],
+				CommunicationProfileID: profiles["Profile B"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[0],
 				Name:                   names[0],
@@ -244,7 +250,10 @@ func TestNotifications(t *testing.T) {
 			t.Error(err)
 		}
 
-		got := tpl.NotificationDefinitions(profiles)
+		got, err := tpl.NotificationDefinitions(profiles)
+		if err != nil {
+			t.Error(err)
+		}
 
 		want := []Notification{
 			{
@@ -269,7 +278,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[0],
 				Name:                   names[0],
@@ -297,7 +306,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile B"],
+				CommunicationProfileID: profiles["Profile B"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[0],
 				Name:                   names[0],
@@ -324,7 +333,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[1],
 				Name:                   names[1],
@@ -352,7 +361,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile B"],
+				CommunicationProfileID: profiles["Profile B"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[1],
 				Name:                   names[1],
@@ -380,7 +389,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[2],
 				Name:                   names[2],
@@ -408,7 +417,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile B"],
+				CommunicationProfileID: profiles["Profile B"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[2],
 				Name:                   names[2],
@@ -435,7 +444,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile A"],
+				CommunicationProfileID: profiles["Profile A"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[3],
 				Name:                   names[3],
@@ -463,7 +472,7 @@ func TestNotifications(t *testing.T) {
 					RequiredAuth:  true,
 				},
 				CalloutActive:          true,
-				CommunicationProfileID: profiles["Profile B"],
+				CommunicationProfileID: profiles["Profile B"][0],
 				Description:            managedNotificationDescription,
 				EventTypeName:          names[3],
 				Name:                   names[3],
@@ -538,4 +547,234 @@ func TestNotificationDiff(t *testing.T) {
 
 		assertEqual(got, want, t)
 	})
+
+	t.Run("remote callout differs from template", func(t *testing.T) {
+		template := []Notification{
+			{
+				Active:                 true,
+				CalloutActive:          true,
+				CommunicationProfileID: "profile-id-123",
+				EventTypeName:          "znt-Account-onUpdate",
+				Callout: Callout{
+					CalloutBaseURL: "https://example.com/callout",
+					CalloutParams:  map[string]string{"AccountName": "<Account.Name>"},
+				},
+			},
+		}
+
+		remote := []Notification{
+			{
+				Active:                 true,
+				CalloutActive:          true,
+				CommunicationProfileID: "profile-id-123",
+				EventTypeName:          "znt-Account-onUpdate",
+				Callout: Callout{
+					CalloutBaseURL: "https://example.com/callout",
+					CalloutParams:  map[string]string{"AccountNumber": "<Account.Number>"},
+				},
+			},
+		}
+
+		got := NewNotificationDiff(template, remote)
+
+		want := NotificationDiff{
+			Update: []NotificationUpdate{{Remote: remote[0], Template: template[0]}},
+		}
+
+		assertEqual(got, want, t)
+
+		if fields := got.Update[0].Fields; !reflect.DeepEqual(fields, []string{"Callout.CalloutParams"}) {
+			t.Errorf("Fields: got %v want %v", fields, []string{"Callout.CalloutParams"})
+		}
+	})
+
+	t.Run("remote is not sorted", func(t *testing.T) {
+		template := []Notification{
+			{
+				Active:                 true,
+				CommunicationProfileID: "profile-id-123",
+				EventTypeName:          "znt-Account-onUpdate",
+			},
+			{
+				Active:                 true,
+				CommunicationProfileID: "profile-id-123",
+				EventTypeName:          "znt-Account-onInsert",
+			},
+		}
+
+		remote := []Notification{template[0], template[1]}
+
+		got := NewNotificationDiff(template, remote)
+
+		assertEqual(got, NotificationDiff{}, t)
+	})
+}
+
+func TestCalloutOverrides(t *testing.T) {
+	tpl, err := Parse(strings.NewReader(`
+callout:
+  calloutAuth:
+    username: janedoe
+    password: verysecret
+  calloutBaseurl: https://example.com/callout
+profiles:
+  - Profile A
+notifications:
+  - baseObject: Account
+    callout:
+      httpMethod: GET
+      requiredAuth: false
+    triggers:
+      - name: insert
+        condition: changeType == 'INSERT'
+      - name: update
+        condition: changeType == 'UPDATE'
+        callout:
+          calloutBaseurl: https://other.example.com/callout
+          httpMethod: PUT
+  - baseObject: Subscription
+    triggers:
+      - name: insert
+        condition: changeType == 'INSERT'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tpl.NotificationDefinitions(map[string][]string{"Profile A": {"123456789"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		baseURL      string
+		httpMethod   string
+		requiredAuth bool
+	}{
+		{"https://example.com/callout", "GET", false},
+		{"https://other.example.com/callout", "PUT", false},
+		{"https://example.com/callout", "POST", true},
+	}
+
+	for i, w := range want {
+		c := got[i].Callout
+		if c.CalloutBaseURL != w.baseURL || c.HTTPMethod != w.httpMethod || c.RequiredAuth != w.requiredAuth {
+			t.Errorf("%s: got %s %s requiredAuth=%t want %s %s requiredAuth=%t",
+				got[i].Name, c.HTTPMethod, c.CalloutBaseURL, c.RequiredAuth, w.httpMethod, w.baseURL, w.requiredAuth)
+		}
+
+		if c.CalloutAuth.Username != "janedoe" {
+			t.Errorf("%s: got username %q want the shared one", got[i].Name, c.CalloutAuth.Username)
+		}
+	}
+
+	overrides := tpl.CalloutOverrides()
+	wantOverrides := map[string]string{
+		"znt-Account-onInsert": "httpMethod=GET requiredAuth=false",
+		"znt-Account-onUpdate": "calloutBaseurl=https://other.example.com/callout httpMethod=PUT requiredAuth=false",
+	}
+
+	if !reflect.DeepEqual(overrides, wantOverrides) {
+		t.Errorf("got %v want %v", overrides, wantOverrides)
+	}
+}
+
+func TestNotificationProfiles(t *testing.T) {
+	profiles := map[string][]string{
+		"Brand A": {"profile-a"},
+		"Brand B": {"profile-b"},
+		"Brand C": {"profile-c"},
+	}
+
+	tpl, err := Parse(strings.NewReader(`
+profiles: [Brand A, Brand B, Brand C]
+notifications:
+  - baseObject: Account
+    triggers: [{name: insert, condition: "changeType == 'INSERT'"}]
+  - baseObject: Invoice
+    profiles: [Brand B]
+    triggers: [{name: insert, condition: "changeType == 'INSERT'"}]
+  - baseObject: Payment
+    excludeProfiles: [Brand A]
+    triggers: [{name: insert, condition: "changeType == 'INSERT'"}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	definitions, err := tpl.NotificationDefinitions(profiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(definitions))
+	for _, n := range definitions {
+		got = append(got, n.String())
+	}
+
+	want := []string{
+		"(profile-a) znt-Account-onInsert",
+		"(profile-b) znt-Account-onInsert",
+		"(profile-c) znt-Account-onInsert",
+		"(profile-b) znt-Invoice-onInsert",
+		"(profile-b) znt-Payment-onInsert",
+		"(profile-c) znt-Payment-onInsert",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	tpl.Notifications[2].ExcludeProfiles = []string{"Brand D"}
+	if _, err := tpl.NotificationDefinitions(profiles); err == nil {
+		t.Errorf("got no error for an excluded profile which is not selected")
+	}
+}
+
+func TestEmailNotifications(t *testing.T) {
+	tpl, err := Parse(strings.NewReader(`
+profiles: [Profile A]
+notifications:
+  - baseObject: Invoice
+    emailTemplateId: email-template-123
+    calloutActive: false
+    triggers: [{name: posted, condition: "changeType == 'UPDATE'"}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tpl.NotificationDefinitions(map[string][]string{"Profile A": {"profile-id-123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Notification{
+		{
+			Active:                 true,
+			CommunicationProfileID: "profile-id-123",
+			Description:            managedNotificationDescription,
+			EmailActive:            true,
+			EmailTemplateID:        "email-template-123",
+			EventTypeName:          "znt-Invoice-onPosted",
+			Name:                   "znt-Invoice-onPosted",
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+
+	if definition := got[0].toAPI(); definition.Callout != nil {
+		t.Errorf("got callout %v want no callout for an email notification", definition.Callout)
+	}
+
+	remote := want[0]
+	remote.EmailTemplateID = "email-template-456"
+	remote.Callout = Callout{CalloutBaseURL: "https://example.com/ignored"}
+
+	d := NewNotificationDiff(got, []Notification{remote})
+	if len(d.Update) != 1 || !reflect.DeepEqual(d.Update[0].Fields, []string{"EmailTemplateID"}) {
+		t.Errorf("got updates %v want the email template to change", d.Update)
+	}
 }
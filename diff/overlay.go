@@ -0,0 +1,249 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OverlayPath returns the overlay file of the environment for the template:
+// "template.<env>.yaml" next to a template file, or "overlays/<env>.yaml" in a
+// template directory (or their .yml and .json equivalents). It returns an
+// empty string when the environment has no overlay.
+func OverlayPath(templatePath, env string) (string, error) {
+	info, err := os.Stat(templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Join(templatePath, "overlays", env)
+	if !info.IsDir() {
+		base = strings.TrimSuffix(templatePath, filepath.Ext(templatePath)) + "." + env
+	}
+
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ApplyOverlay returns the template overridden by the overlay file. The
+// overlay objects are merged into the template ones, a null value removes the
+// field, and the other values (including the profiles list) replace the
+// template ones. The notifications are matched by base object and custom
+// event type or scheduled event, the scheduled events by base object and
+// name, and the others by name. The body files are read once the overlay is
+// applied.
+func (t *Template) ApplyOverlay(path string) (*Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := toJSON(content)
+	if err != nil {
+		return nil, &ParseError{File: path, Err: err}
+	}
+
+	var overlay map[string]interface{}
+	if err := json.Unmarshal(doc.content, &overlay); err != nil {
+		return nil, &ParseError{File: path, Err: fmt.Errorf("overlay must be an object: %w", err)}
+	}
+
+	// the body files are read again once the overlay is applied, the overlay
+	// may change them
+	unread := *t
+	unread.EmailTemplates = make([]EmailTemplateTemplate, len(t.EmailTemplates))
+	for i, e := range t.EmailTemplates {
+		if e.BodyFile != "" {
+			e.Body = ""
+		}
+		unread.EmailTemplates[i] = e
+	}
+
+	base, err := toMap(&unread)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mergeObject(base, overlay, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	merged, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Template
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// notifications are matched in place, never added by an overlay
+	for i := range result.Notifications {
+		result.Notifications[i].source = t.Notifications[i].source
+	}
+
+	// a body file set by the overlay is relative to the overlay file
+	for i, e := range result.EmailTemplates {
+		for _, original := range t.EmailTemplates {
+			if original.Name == e.Name {
+				result.EmailTemplates[i].source = original.source
+				result.EmailTemplates[i].bodyDir = original.bodyDir
+				if e.BodyFile != original.BodyFile {
+					result.EmailTemplates[i].bodyDir = filepath.Dir(path)
+				}
+			}
+		}
+	}
+
+	if err := readBodyFiles(&result); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &result, nil
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	err = json.Unmarshal(content, &result)
+	return result, err
+}
+
+// lookupKey returns the key of the object matching the name, the template
+// keys are case insensitive
+func lookupKey(object map[string]interface{}, name string) (string, bool) {
+	if _, ok := object[name]; ok {
+		return name, true
+	}
+
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// matchKeys identify the elements of the arrays merged by an overlay, a
+// notification is identified by its base object and the custom event type or
+// scheduled event it fires on
+var matchKeys = map[string][]string{
+	"notifications":          {"baseObject", "eventType", "scheduledEvent"},
+	"notifications.triggers": {"name"},
+	"emailtemplates":         {"name"},
+	"eventtypes":             {"name"},
+	"scheduledevents":        {"baseObject", "name"},
+	"communicationprofiles":  {"name"},
+}
+
+func mergeObject(base, overlay map[string]interface{}, path string) error {
+	for name, value := range overlay {
+		key, exists := lookupKey(base, name)
+		if !exists {
+			key = name
+		}
+
+		fieldPath := strings.ToLower(name)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		if value == nil {
+			delete(base, key)
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if current, ok := base[key].(map[string]interface{}); ok {
+				if err := mergeObject(current, v, fieldPath); err != nil {
+					return err
+				}
+				continue
+			}
+
+		case []interface{}:
+			if keys, ok := matchKeys[fieldPath]; ok {
+				current, _ := base[key].([]interface{})
+				if err := mergeArray(current, v, fieldPath, keys); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		base[key] = value
+	}
+
+	return nil
+}
+
+// mergeArray merges each overlay object into the base object with the same
+// match keys, a missing key matches an empty value
+func mergeArray(base, overlay []interface{}, path string, keys []string) error {
+	for _, o := range overlay {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected objects", path)
+		}
+
+		id := matchID(object, keys)
+		if id == matchID(nil, keys) {
+			return fmt.Errorf("%s: every object must have a %s", path, strings.Join(keys, " or "))
+		}
+
+		var target map[string]interface{}
+		for _, b := range base {
+			candidate, _ := b.(map[string]interface{})
+			if matchID(candidate, keys) != id {
+				continue
+			}
+
+			if target != nil {
+				return fmt.Errorf("%s: %s matches more than one object of the template", path, id)
+			}
+			target = candidate
+		}
+
+		if target == nil {
+			return fmt.Errorf("%s: no %s in the template", path, id)
+		}
+
+		if err := mergeObject(target, object, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchID describes the match keys of the object, like "baseObject Account"
+func matchID(object map[string]interface{}, keys []string) string {
+	values := make([]string, 0, len(keys))
+	for _, matchKey := range keys {
+		var value interface{} = ""
+		if key, ok := lookupKey(object, matchKey); ok && object[key] != nil {
+			value = object[key]
+		}
+
+		if value != "" {
+			values = append(values, fmt.Sprintf("%s %v", matchKey, value))
+		}
+	}
+
+	return strings.Join(values, ", ")
+}
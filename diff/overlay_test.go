@@ -0,0 +1,393 @@
+package diff
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	base := `
+callout:
+  calloutAuth:
+    username: janedoe
+  calloutBaseurl: https://sandbox.example.com/callout
+profiles:
+  - Sandbox Profile
+notifications:
+  - baseObject: Account
+    triggers:
+      - name: insert
+        condition: changeType == 'INSERT'
+    calloutParams:
+      AccountName: <Account.Name>
+`
+
+	write := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("overrides the callout, profiles and notification fields", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base)
+		write(t, filepath.Join(dir, "template.staging.yml"), `
+callout:
+  calloutBaseurl: https://staging.example.com/callout
+profiles:
+  - Staging Profile A
+  - Staging Profile B
+notifications:
+  - baseObject: Account
+    triggers:
+      - name: insert
+        condition: changeType == 'INSERT' && Account.Status == 'Active'
+    calloutParams:
+      AccountNumber: <Account.Number>
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path, err := OverlayPath(filepath.Join(dir, "template.yaml"), "staging")
+		if err != nil || path != filepath.Join(dir, "template.staging.yml") {
+			t.Fatalf("got %q (%v) want the staging overlay", path, err)
+		}
+
+		got, err := tpl.ApplyOverlay(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Callout.CalloutBaseURL != "https://staging.example.com/callout" || got.Callout.CalloutAuth.Username != "janedoe" {
+			t.Errorf("Callout: got %v", got.Callout)
+		}
+
+		if len(got.Profiles) != 2 || got.Profiles[0] != "Staging Profile A" {
+			t.Errorf("Profiles: got %v", got.Profiles)
+		}
+
+		n := got.Notifications[0]
+		if len(n.Triggers) != 1 || !strings.Contains(n.Triggers[0].Condition, "Active") {
+			t.Errorf("Triggers: got %v", n.Triggers)
+		}
+
+		if len(n.CalloutParams) != 2 {
+			t.Errorf("CalloutParams: got %v want both params", n.CalloutParams)
+		}
+
+		if tpl.Callout.CalloutBaseURL != "https://sandbox.example.com/callout" {
+			t.Errorf("the base template was modified")
+		}
+	})
+
+	t.Run("rejects a notification missing from the template", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base)
+		write(t, filepath.Join(dir, "template.production.json"), `{"notifications": [{"baseObject": "Invoice"}]}`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tpl.ApplyOverlay(filepath.Join(dir, "template.production.json"))
+		if err == nil || !strings.Contains(err.Error(), "Invoice") {
+			t.Errorf("got %v want an unknown Invoice notification error", err)
+		}
+	})
+
+	t.Run("matches the notifications by base object and event", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+  - baseObject: Invoice
+    triggers:
+      - name: posted
+        condition: changeType == 'UPDATE' && Invoice.Status == 'Posted'
+  - baseObject: Invoice
+    scheduledEvent: due
+    triggers: []
+  - eventType: PaymentRetried
+    triggers: []
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+notifications:
+  - baseObject: Invoice
+    scheduledEvent: due
+    calloutParams:
+      DueDate: <Invoice.DueDate>
+  - eventType: PaymentRetried
+    calloutParams:
+      PaymentID: <Payment.Id>
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := got.Notifications[1]; len(n.CalloutParams) != 0 || len(n.Triggers) != 1 {
+			t.Errorf("Invoice triggers notification: got %v want it unchanged", n)
+		}
+
+		if n := got.Notifications[2]; n.CalloutParams["DueDate"] == "" || len(n.Triggers) != 0 {
+			t.Errorf("Invoice scheduled notification: got %v want the DueDate param", n)
+		}
+
+		if n := got.Notifications[3]; n.CalloutParams["PaymentID"] == "" {
+			t.Errorf("PaymentRetried notification: got %v want the PaymentID param", n)
+		}
+	})
+
+	t.Run("rejects an ambiguous notification", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+  - baseObject: Account
+    triggers:
+      - name: update
+        condition: changeType == 'UPDATE'
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+notifications:
+  - baseObject: Account
+    calloutParams:
+      AccountNumber: <Account.Number>
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "more than one") {
+			t.Errorf("got %v want an ambiguous notification error", err)
+		}
+	})
+
+	t.Run("merges the email templates by name", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "overlays"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+emailTemplates:
+  - name: welcome
+    eventTypeName: znt-Account-onInsert
+    subject: Welcome
+    bodyFile: welcome.html
+  - name: goodbye
+    eventTypeName: znt-Account-onInsert
+    subject: Goodbye
+    body: See you
+`)
+		write(t, filepath.Join(dir, "welcome.html"), "<p>Welcome</p>")
+		write(t, filepath.Join(dir, "overlays", "staging.html"), "<p>Welcome to staging</p>")
+		write(t, filepath.Join(dir, "overlays", "staging.yaml"), `
+emailTemplates:
+  - name: goodbye
+    subject: Goodbye from staging
+`)
+		write(t, filepath.Join(dir, "overlays", "qa.yaml"), `
+emailTemplates:
+  - name: welcome
+    bodyFile: staging.html
+`)
+
+		tpl, err := ParseFile(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tpl.ApplyOverlay(filepath.Join(dir, "overlays", "staging.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got.EmailTemplates) != 2 {
+			t.Fatalf("got %v want both email templates", got.EmailTemplates)
+		}
+
+		if e := got.EmailTemplates[0]; e.Body != "<p>Welcome</p>" || e.Subject != "Welcome" {
+			t.Errorf("welcome: got %v want it unchanged", e)
+		}
+
+		if e := got.EmailTemplates[1]; e.Subject != "Goodbye from staging" || e.EventTypeName != "znt-Account-onInsert" || e.Body != "See you" {
+			t.Errorf("goodbye: got %v want only the subject overridden", e)
+		}
+
+		got, err = tpl.ApplyOverlay(filepath.Join(dir, "overlays", "qa.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if e := got.EmailTemplates[0]; e.Body != "<p>Welcome to staging</p>" {
+			t.Errorf("welcome: got body %q want the overlay body file", e.Body)
+		}
+	})
+
+	t.Run("rejects an email template missing from the template", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+emailTemplates:
+  - name: welcome
+    eventTypeName: znt-Account-onInsert
+    subject: Welcome
+    body: Welcome
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+emailTemplates:
+  - name: welcome
+    subject: Welcome to staging
+  - name: goodbye
+    subject: Goodbye
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "goodbye") {
+			t.Errorf("got %v want an unknown goodbye email template error", err)
+		}
+	})
+
+	t.Run("merges the custom event types by name", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+eventTypes:
+  - name: order-shipped
+    displayName: Order shipped
+    namespace: user.notification
+  - name: order-cancelled
+    displayName: Order cancelled
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+eventTypes:
+  - name: order-cancelled
+    description: Published by the staging warehouse
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []EventTypeTemplate{
+			{Name: "order-shipped", DisplayName: "Order shipped", Namespace: "user.notification"},
+			{Name: "order-cancelled", DisplayName: "Order cancelled", Description: "Published by the staging warehouse"},
+		}
+		if !reflect.DeepEqual(got.EventTypes, want) {
+			t.Errorf("got %v want %v", got.EventTypes, want)
+		}
+	})
+
+	t.Run("merges the scheduled events by base object and name", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+scheduledEvents:
+  - name: due
+    baseObject: Invoice
+    field: DueDate
+    condition: Balance > 0
+    parameters:
+      Balance:
+        displayName: Balance
+  - name: due
+    baseObject: Subscription
+    field: TermEndDate
+    condition: Status == 'Active'
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+scheduledEvents:
+  - name: due
+    baseObject: Invoice
+    hours: 6
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got.ScheduledEvents) != 2 {
+			t.Fatalf("got %v want both scheduled events", got.ScheduledEvents)
+		}
+
+		if s := got.ScheduledEvents[0]; s.Hours != 6 || s.Field != "DueDate" || s.Condition != "Balance > 0" || len(s.Parameters) != 1 {
+			t.Errorf("Invoice: got %+v want only the hours overridden", s)
+		}
+
+		if s := got.ScheduledEvents[1]; s.Hours != 0 || s.Field != "TermEndDate" {
+			t.Errorf("Subscription: got %+v want it unchanged", s)
+		}
+	})
+
+	t.Run("merges the communication profiles by name", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "template.yaml"), base+`
+communicationProfiles:
+  - name: Brand A
+    description: Brand A accounts
+  - name: Brand B
+    description: Brand B accounts
+`)
+		write(t, filepath.Join(dir, "template.staging.yaml"), `
+communicationProfiles:
+  - name: Brand B
+    adopt: true
+`)
+
+		tpl, err := ParseFile(filepath.Join(dir, "template.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := tpl.ApplyOverlay(filepath.Join(dir, "template.staging.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []CommunicationProfileTemplate{
+			{Name: "Brand A", Description: "Brand A accounts"},
+			{Name: "Brand B", Description: "Brand B accounts", Adopt: true},
+		}
+		if !reflect.DeepEqual(got.CommunicationProfiles, want) {
+			t.Errorf("got %v want %v", got.CommunicationProfiles, want)
+		}
+	})
+
+	t.Run("environments without overlay", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "base.yaml"), base)
+
+		path, err := OverlayPath(dir, "staging")
+		if err != nil || path != "" {
+			t.Errorf("got %q (%v) want no overlay", path, err)
+		}
+	})
+}
@@ -0,0 +1,361 @@
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// planVersion is bumped whenever the plan file format changes
+const planVersion = 2
+
+// Plan contains the diffs to apply to a Zuora environment, along with what is
+// needed to detect a drift of that environment before applying them
+type Plan struct {
+	Version           int
+	TemplateHash      string
+	BaseURL           string
+	RemoteFingerprint string
+	Triggers          TriggerDiff
+	Notifications     NotificationDiff
+	EmailTemplates    EmailTemplateDiff
+	EventTypes        CustomEventTypeDiff
+	ScheduledEvents   ScheduledEventDiff
+
+	CommunicationProfiles CommunicationProfileDiff
+}
+
+// Remote is the state of the targeted Zuora environment
+type Remote struct {
+	Triggers        []Trigger
+	Notifications   []Notification
+	Profiles        map[string][]string
+	EmailTemplates  []EmailTemplate
+	EventTypes      []CustomEventType
+	ScheduledEvents []ScheduledEvent
+
+	CommunicationProfiles []CommunicationProfile
+}
+
+// FetchRemote retrieves the managed resources and the profiles from Zuora
+func FetchRemote(c *zuora.Client) (*Remote, error) {
+	triggers, err := FetchManagedTriggers(c)
+	if err != nil {
+		return nil, err
+	}
+
+	communicationProfiles, err := FetchCommunicationProfiles(c)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := FetchManagedNotifications(c)
+	if err != nil {
+		return nil, err
+	}
+
+	emailTemplates, err := FetchManagedEmailTemplates(c)
+	if err != nil {
+		return nil, err
+	}
+
+	eventTypes, err := FetchManagedCustomEventTypes(c)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledEvents, err := FetchManagedScheduledEvents(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Remote{
+		Triggers:        triggers,
+		Notifications:   notifications,
+		Profiles:        profileIDsByName(communicationProfiles),
+		EmailTemplates:  emailTemplates,
+		EventTypes:      eventTypes,
+		ScheduledEvents: scheduledEvents,
+
+		CommunicationProfiles: communicationProfiles,
+	}, nil
+}
+
+// Fingerprint hashes the remote state, independently of the order of its resources
+func (r *Remote) Fingerprint() string {
+	triggers := make([]Trigger, len(r.Triggers))
+	copy(triggers, r.Triggers)
+	sort.Slice(triggers, func(i, j int) bool { return triggers[i].ID < triggers[j].ID })
+
+	notifications := make([]Notification, len(r.Notifications))
+	copy(notifications, r.Notifications)
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID < notifications[j].ID })
+
+	emailTemplates := make([]EmailTemplate, len(r.EmailTemplates))
+	copy(emailTemplates, r.EmailTemplates)
+	sort.Slice(emailTemplates, func(i, j int) bool { return emailTemplates[i].ID < emailTemplates[j].ID })
+
+	eventTypes := make([]CustomEventType, len(r.EventTypes))
+	copy(eventTypes, r.EventTypes)
+	sort.Slice(eventTypes, func(i, j int) bool { return eventTypes[i].ID < eventTypes[j].ID })
+
+	scheduledEvents := make([]ScheduledEvent, len(r.ScheduledEvents))
+	copy(scheduledEvents, r.ScheduledEvents)
+	sort.Slice(scheduledEvents, func(i, j int) bool { return scheduledEvents[i].ID < scheduledEvents[j].ID })
+
+	communicationProfiles := make([]CommunicationProfile, len(r.CommunicationProfiles))
+	copy(communicationProfiles, r.CommunicationProfiles)
+	sort.Slice(communicationProfiles, func(i, j int) bool { return communicationProfiles[i].ID < communicationProfiles[j].ID })
+
+	// maps are marshalled with sorted keys
+	return hash(struct {
+		Triggers              []Trigger
+		Notifications         []Notification
+		Profiles              map[string][]string
+		EmailTemplates        []EmailTemplate
+		EventTypes            []CustomEventType
+		ScheduledEvents       []ScheduledEvent
+		CommunicationProfiles []CommunicationProfile
+	}{triggers, notifications, r.Profiles, emailTemplates, eventTypes, scheduledEvents, communicationProfiles})
+}
+
+// Hash returns the SHA-256 of the template
+func (t *Template) Hash() string {
+	return hash(t)
+}
+
+func hash(v interface{}) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		// the hashed types only contain strings, booleans, slices and maps
+		panic(err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPlan computes the diffs between the template and the remote state
+func NewPlan(t *Template, r *Remote, baseURL string) (*Plan, error) {
+	// the declared profiles are matched by name
+	for _, p := range t.CommunicationProfiles {
+		if IDs := r.Profiles[p.Name]; len(IDs) > 1 {
+			return nil, fmt.Errorf("communication profile name %q is shared by profiles %s", p.Name, strings.Join(IDs, " and "))
+		}
+	}
+
+	// the notifications of the profiles to create reference their pending ID
+	profiles := NewCommunicationProfileDiff(t.CommunicationProfiles, r.CommunicationProfiles)
+
+	definitions, err := t.NotificationDefinitions(profiles.ProfileIDs(r.Profiles))
+	if err != nil {
+		return nil, err
+	}
+
+	emailTemplateIDs := make(map[string]string)
+	for _, e := range r.EmailTemplates {
+		emailTemplateIDs[e.Name] = e.ID
+	}
+	resolveEmailTemplates(definitions, emailTemplateIDs)
+
+	return &Plan{
+		Version:           planVersion,
+		TemplateHash:      t.Hash(),
+		BaseURL:           baseURL,
+		RemoteFingerprint: r.Fingerprint(),
+		Triggers:          NewTriggerDiff(t.Triggers(), r.Triggers),
+		Notifications:     NewNotificationDiff(definitions, r.Notifications),
+		EmailTemplates:    NewEmailTemplateDiff(t.EmailTemplateDefinitions(), r.EmailTemplates),
+		EventTypes:        NewCustomEventTypeDiff(t.CustomEventTypes(), r.EventTypes),
+		ScheduledEvents:   NewScheduledEventDiff(t.ScheduledEventDefinitions(), r.ScheduledEvents),
+
+		CommunicationProfiles: profiles,
+	}, nil
+}
+
+// Empty returns true when there is nothing to apply
+func (p *Plan) Empty() bool {
+	return len(p.Triggers.Add) == 0 && len(p.Triggers.Remove) == 0 && len(p.Triggers.Update) == 0 &&
+		len(p.Notifications.Add) == 0 && len(p.Notifications.Remove) == 0 && len(p.Notifications.Update) == 0 &&
+		len(p.EmailTemplates.Add) == 0 && len(p.EmailTemplates.Remove) == 0 && len(p.EmailTemplates.Update) == 0 &&
+		len(p.EventTypes.Add) == 0 && len(p.EventTypes.Remove) == 0 && len(p.EventTypes.Update) == 0 &&
+		len(p.ScheduledEvents.Add) == 0 && len(p.ScheduledEvents.Remove) == 0 && len(p.ScheduledEvents.Update) == 0 &&
+		len(p.CommunicationProfiles.Add) == 0 && len(p.CommunicationProfiles.Remove) == 0 && len(p.CommunicationProfiles.Update) == 0
+}
+
+// Pending returns true when there are changes to apply, ignoring the
+// updates which only reactivate a disabled resource if requested
+func (p *Plan) Pending(ignoreActivations bool) bool {
+	if !ignoreActivations {
+		return !p.Empty()
+	}
+
+	if len(p.Triggers.Add) > 0 || len(p.Triggers.Remove) > 0 ||
+		len(p.Notifications.Add) > 0 || len(p.Notifications.Remove) > 0 ||
+		len(p.EmailTemplates.Add) > 0 || len(p.EmailTemplates.Remove) > 0 ||
+		len(p.EventTypes.Add) > 0 || len(p.EventTypes.Remove) > 0 ||
+		len(p.ScheduledEvents.Add) > 0 || len(p.ScheduledEvents.Remove) > 0 ||
+		len(p.CommunicationProfiles.Add) > 0 || len(p.CommunicationProfiles.Remove) > 0 ||
+		len(p.CommunicationProfiles.Update) > 0 {
+		return true
+	}
+
+	for _, u := range p.Triggers.Update {
+		if !u.Activation() {
+			return true
+		}
+	}
+
+	for _, u := range p.Notifications.Update {
+		if !u.Activation() {
+			return true
+		}
+	}
+
+	for _, u := range p.EmailTemplates.Update {
+		if !u.Activation() {
+			return true
+		}
+	}
+
+	for _, u := range p.EventTypes.Update {
+		if !u.Activation() {
+			return true
+		}
+	}
+
+	for _, u := range p.ScheduledEvents.Update {
+		if !u.Activation() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verify returns an error when the plan was not made for the remote state
+func (p *Plan) Verify(r *Remote, baseURL string) error {
+	if p.Version != planVersion {
+		return fmt.Errorf("plan version %d is not supported, expected %d", p.Version, planVersion)
+	}
+
+	if p.BaseURL != baseURL {
+		return fmt.Errorf("plan was made for %s, not %s", p.BaseURL, baseURL)
+	}
+
+	if p.RemoteFingerprint != r.Fingerprint() {
+		return fmt.Errorf("remote state of %s has changed since the plan was made", baseURL)
+	}
+
+	return nil
+}
+
+// Apply the plan to the targeted Zuora environment, in phases: the
+// notifications reference the profiles, the event types (of the triggers,
+// custom or scheduled) and the email templates, so they are removed before
+// their references are deleted, and created once their references exist. A
+// failed activation does not stop the apply, the failed activations are
+// returned together as an ActivationError once every phase ran
+func (p *Plan) Apply(c *zuora.Client) error {
+	var activated activations
+
+	// 1. remove the notifications
+	if err := p.Notifications.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	// 2. create and update the referenced resources
+	profiles, err := p.CommunicationProfiles.ApplyAdd(c)
+	if err != nil {
+		return err
+	}
+	p.Notifications.resolveProfiles(profiles)
+
+	if err := p.Triggers.ApplyAdd(c); err != nil {
+		return err
+	}
+
+	if err := activated.merge(p.Triggers.ApplyUpdate(c)); err != nil {
+		return err
+	}
+
+	if err := activated.merge(p.EventTypes.ApplyAdd(c)); err != nil {
+		return err
+	}
+
+	if err := activated.merge(p.ScheduledEvents.ApplyAdd(c)); err != nil {
+		return err
+	}
+
+	emailTemplates, err := p.EmailTemplates.ApplyAdd(c)
+	if err := activated.merge(err); err != nil {
+		return err
+	}
+	p.Notifications.resolveEmailTemplates(emailTemplates)
+
+	// 3. update and create the notifications
+	if err := activated.merge(p.Notifications.ApplyUpdate(c)); err != nil {
+		return err
+	}
+
+	if err := p.Notifications.ApplyAdd(c); err != nil {
+		return err
+	}
+
+	// 4. remove the resources no longer referenced
+	if err := p.EmailTemplates.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	if err := p.EventTypes.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	if err := p.ScheduledEvents.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	if err := p.Triggers.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	if err := p.CommunicationProfiles.ApplyRemove(c); err != nil {
+		return err
+	}
+
+	return activated.err()
+}
+
+func (p *Plan) String() string {
+	return strings.Join([]string{
+		p.CommunicationProfiles.String(),
+		p.Triggers.String(),
+		p.ScheduledEvents.String(),
+		p.EventTypes.String(),
+		p.EmailTemplates.String(),
+		p.Notifications.String(),
+	}, "\n")
+}
+
+// WritePlan saves the plan, it includes the callout credentials
+func WritePlan(w io.Writer, p *Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// ReadPlan loads a plan saved with WritePlan
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var p Plan
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	remote := func() *Remote {
+		return &Remote{
+			Triggers: []Trigger{
+				{ID: "trigger-1", BaseObject: "Account", Condition: "changeType == 'INSERT'"},
+				{ID: "trigger-2", BaseObject: "Account", Condition: "changeType == 'UPDATE'"},
+			},
+			Notifications: []Notification{
+				{ID: "notification-1", CommunicationProfileID: "profile-id-123", EventTypeName: "znt-Account-onInsert"},
+			},
+			Profiles: map[string][]string{"Profile A": {"profile-id-123"}},
+		}
+	}
+
+	t.Run("fingerprint does not depend on the order of the resources", func(t *testing.T) {
+		a := remote()
+		b := remote()
+		b.Triggers[0], b.Triggers[1] = b.Triggers[1], b.Triggers[0]
+
+		if a.Fingerprint() != b.Fingerprint() {
+			t.Errorf("got different fingerprints for the same resources")
+		}
+	})
+
+	t.Run("refuses to apply when the remote state has changed", func(t *testing.T) {
+		plan := &Plan{
+			Version:           planVersion,
+			BaseURL:           "https://rest.zuora.com",
+			RemoteFingerprint: remote().Fingerprint(),
+		}
+
+		if err := plan.Verify(remote(), "https://rest.zuora.com"); err != nil {
+			t.Errorf("got %v want no error", err)
+		}
+
+		if err := plan.Verify(remote(), "https://rest.apisandbox.zuora.com"); err == nil {
+			t.Errorf("got no error for another base URL")
+		}
+
+		drifted := remote()
+		drifted.Triggers[1].Active = true
+		if err := plan.Verify(drifted, "https://rest.zuora.com"); err == nil {
+			t.Errorf("got no error for a drifted remote state")
+		}
+	})
+
+	t.Run("is read back as written", func(t *testing.T) {
+		plan := &Plan{
+			Version:           planVersion,
+			TemplateHash:      "abc",
+			BaseURL:           "https://rest.zuora.com",
+			RemoteFingerprint: remote().Fingerprint(),
+			Triggers:          TriggerDiff{Remove: remote().Triggers},
+			Notifications:     NotificationDiff{Remove: remote().Notifications},
+		}
+
+		var buf bytes.Buffer
+		if err := WritePlan(&buf, plan); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ReadPlan(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, plan) {
+			t.Errorf("got %v want %v", got, plan)
+		}
+	})
+
+	t.Run("pending changes may ignore the activations", func(t *testing.T) {
+		plan := &Plan{
+			Triggers: TriggerDiff{Update: []TriggerUpdate{{Fields: []string{"Active"}}}},
+			Notifications: NotificationDiff{
+				Update: []NotificationUpdate{{Fields: []string{"Active"}}},
+			},
+		}
+
+		if !plan.Pending(false) {
+			t.Errorf("got no pending changes want the activations")
+		}
+
+		if plan.Pending(true) {
+			t.Errorf("got pending changes want the activations to be ignored")
+		}
+
+		plan.Notifications.Update[0].Fields = []string{"Active", "Callout.CalloutParams"}
+		if !plan.Pending(true) {
+			t.Errorf("got no pending changes want the callout params update")
+		}
+
+		plan.Notifications.Update[0].Fields = []string{"Active"}
+		plan.Triggers.Update[0].Fields = []string{"Condition"}
+		if !plan.Pending(true) {
+			t.Errorf("got no pending changes want the trigger condition update")
+		}
+	})
+
+	t.Run("keeps the inactive resources inactive", func(t *testing.T) {
+		plan := &Plan{
+			Triggers: TriggerDiff{Update: []TriggerUpdate{
+				{Template: Trigger{Active: true}, Fields: []string{"Active"}},
+				{Template: Trigger{Active: true}, Fields: []string{"Active", "Condition"}},
+				{Remote: Trigger{Active: true}, Template: Trigger{Active: true}, Fields: []string{"Condition"}},
+			}},
+			Notifications: NotificationDiff{Update: []NotificationUpdate{
+				{Template: Notification{Active: true}, Fields: []string{"Active"}},
+			}},
+		}
+
+		plan.KeepInactive()
+
+		want := []TriggerUpdate{
+			{Template: Trigger{Active: false}, Fields: []string{"Condition"}},
+			{Remote: Trigger{Active: true}, Template: Trigger{Active: true}, Fields: []string{"Condition"}},
+		}
+		if !reflect.DeepEqual(plan.Triggers.Update, want) {
+			t.Errorf("got %v want %v", plan.Triggers.Update, want)
+		}
+
+		if len(plan.Notifications.Update) != 0 {
+			t.Errorf("got %v want the activation to be dropped", plan.Notifications.Update)
+		}
+	})
+}
@@ -1,7 +1,156 @@
 package diff
 
-// Profile is a Zuora communication profile, each customer account is associated to one
-type Profile struct {
-	ID   string
-	Name string `json:"ProfileName"`
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// allProfiles is the profile rule selecting every communication profile
+const allProfiles = "all"
+
+// ResolvedProfile is a communication profile selected by a profile rule
+type ResolvedProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+}
+
+// ResolveProfiles returns the profiles selected by the rules, in the order of
+// the rules then by name, each profile once. A rule is either an explicit
+// "id:<ID>", the exact profile name, which must not be shared by several
+// profiles, or else "all", a "/regex/" or a glob on the profile name.
+func ResolveProfiles(rules []string, profileIDsByName map[string][]string) ([]ResolvedProfile, error) {
+	names := make([]string, 0, len(profileIDsByName))
+	nameByID := make(map[string]string, len(profileIDsByName))
+	for name, IDs := range profileIDsByName {
+		names = append(names, name)
+		for _, ID := range IDs {
+			nameByID[ID] = name
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]ResolvedProfile, 0)
+	seen := make(map[string]bool)
+
+	add := func(rule, name, ID string) {
+		if !seen[ID] {
+			seen[ID] = true
+			result = append(result, ResolvedProfile{ID: ID, Name: name, Rule: rule})
+		}
+	}
+
+	for _, rule := range rules {
+		// a profile named like a pattern, or "all", is selected by its name
+		IDs, exact := profileIDsByName[rule]
+
+		switch {
+		case strings.HasPrefix(rule, "id:"):
+			ID := strings.TrimPrefix(rule, "id:")
+			name, ok := nameByID[ID]
+			if !ok {
+				return nil, fmt.Errorf("profile %q not found in Zuora environment", rule)
+			}
+			add(rule, name, ID)
+
+		case exact:
+			if len(IDs) > 1 {
+				return nil, fmt.Errorf("profile name %q is shared by profiles %s, select one with id:<ID>", rule, strings.Join(IDs, " and "))
+			}
+			add(rule, rule, IDs[0])
+
+		case rule == allProfiles || isProfilePattern(rule):
+			match, err := profileMatcher(rule)
+			if err != nil {
+				return nil, err
+			}
+
+			matched := false
+			for _, name := range names {
+				if match(name) {
+					matched = true
+					for _, ID := range profileIDsByName[name] {
+						add(rule, name, ID)
+					}
+				}
+			}
+
+			if !matched {
+				return nil, fmt.Errorf("profile rule %q matches no profile in Zuora environment", rule)
+			}
+
+		default:
+			return nil, fmt.Errorf("profile %q not found in Zuora environment", rule)
+		}
+	}
+
+	return result, nil
+}
+
+// isProfilePattern returns true when the rule is a regex or a glob
+func isProfilePattern(rule string) bool {
+	return len(rule) > 1 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") ||
+		strings.ContainsAny(rule, "*?[")
+}
+
+// profileMatcher returns the function matching the profile names selected by
+// the "all", regex or glob rule
+func profileMatcher(rule string) (func(string) bool, error) {
+	if rule == allProfiles {
+		return func(string) bool { return true }, nil
+	}
+
+	if len(rule) > 1 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+		re, err := regexp.Compile(rule[1 : len(rule)-1])
+		if err != nil {
+			return nil, fmt.Errorf("profile rule %q: %w", rule, err)
+		}
+
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(rule, ""); err != nil {
+		return nil, fmt.Errorf("profile rule %q: %w", rule, err)
+	}
+
+	return func(name string) bool {
+		matched, _ := path.Match(rule, name)
+		return matched
+	}, nil
+}
+
+// ProfileSelection is the profiles resolved for the template, or for one of
+// its notifications when it selects its own profiles
+type ProfileSelection struct {
+	Notification string            `json:"notification,omitempty"`
+	Profiles     []ResolvedProfile `json:"profiles"`
+}
+
+// ProfileSelections resolves the top-level profiles, then the profiles of
+// the notifications selecting or excluding their own
+func (t *Template) ProfileSelections(profileIDsByName map[string][]string) ([]ProfileSelection, error) {
+	profiles, err := ResolveProfiles(t.Profiles, profileIDsByName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ProfileSelection{{Profiles: profiles}}
+
+	for _, n := range t.Notifications {
+		if len(n.Profiles) == 0 && len(n.ExcludeProfiles) == 0 {
+			continue
+		}
+
+		profiles, err := n.profiles(t.Profiles, profileIDsByName)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, ProfileSelection{Notification: n.label(), Profiles: profiles})
+	}
+
+	return result, nil
 }
@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveProfiles(t *testing.T) {
+	profiles := map[string][]string{
+		"Brand A": {"1"},
+		"Brand B": {"2"},
+		"Default": {"3"},
+	}
+
+	tests := []struct {
+		name  string
+		rules []string
+		want  []ResolvedProfile
+	}{
+		{
+			name:  "exact name",
+			rules: []string{"Default"},
+			want:  []ResolvedProfile{{ID: "3", Name: "Default", Rule: "Default"}},
+		},
+		{
+			name:  "explicit ID",
+			rules: []string{"id:2"},
+			want:  []ResolvedProfile{{ID: "2", Name: "Brand B", Rule: "id:2"}},
+		},
+		{
+			name:  "glob",
+			rules: []string{"Brand *"},
+			want: []ResolvedProfile{
+				{ID: "1", Name: "Brand A", Rule: "Brand *"},
+				{ID: "2", Name: "Brand B", Rule: "Brand *"},
+			},
+		},
+		{
+			name:  "regex",
+			rules: []string{"/^(Default|Brand B)$/"},
+			want: []ResolvedProfile{
+				{ID: "2", Name: "Brand B", Rule: "/^(Default|Brand B)$/"},
+				{ID: "3", Name: "Default", Rule: "/^(Default|Brand B)$/"},
+			},
+		},
+		{
+			name:  "all, each profile once with its first rule",
+			rules: []string{"Default", "all"},
+			want: []ResolvedProfile{
+				{ID: "3", Name: "Default", Rule: "Default"},
+				{ID: "1", Name: "Brand A", Rule: "all"},
+				{ID: "2", Name: "Brand B", Rule: "all"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveProfiles(tt.rules, profiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, rule := range []string{"Unknown", "id:42", "Other *", "/[/"} {
+		t.Run("rejects "+rule, func(t *testing.T) {
+			if _, err := ResolveProfiles([]string{rule}, profiles); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("excludes profiles by pattern", func(t *testing.T) {
+		tpl := Template{
+			Profiles: []string{"all"},
+			Notifications: []NotificationTemplate{
+				{BaseObject: "Invoice", ExcludeProfiles: []string{"Brand *"}},
+			},
+		}
+
+		got, err := tpl.ProfileSelections(profiles)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ProfileSelection{
+			{Profiles: []ResolvedProfile{
+				{ID: "1", Name: "Brand A", Rule: "all"},
+				{ID: "2", Name: "Brand B", Rule: "all"},
+				{ID: "3", Name: "Default", Rule: "all"},
+			}},
+			{Notification: "Invoice", Profiles: []ResolvedProfile{
+				{ID: "3", Name: "Default", Rule: "all"},
+			}},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("selects the profiles sharing a name by ID", func(t *testing.T) {
+		shared := map[string][]string{"Brand A": {"1", "4"}, "Default": {"3"}}
+
+		got, err := ResolveProfiles([]string{"id:4", "Default"}, shared)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ResolvedProfile{
+			{ID: "4", Name: "Brand A", Rule: "id:4"},
+			{ID: "3", Name: "Default", Rule: "Default"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+
+		_, err = ResolveProfiles([]string{"Brand A"}, shared)
+		if err == nil || !strings.Contains(err.Error(), "shared by profiles 1 and 4") {
+			t.Errorf("got %v want a shared profile name error", err)
+		}
+	})
+
+	t.Run("selects the exact name before the patterns", func(t *testing.T) {
+		named := map[string][]string{"Brand [EU]": {"1"}, "all": {"2"}, "Brand E": {"3"}}
+
+		got, err := ResolveProfiles([]string{"Brand [EU]", "all"}, named)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ResolvedProfile{
+			{ID: "1", Name: "Brand [EU]", Rule: "Brand [EU]"},
+			{ID: "2", Name: "all", Rule: "all"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
@@ -1,71 +1,20 @@
 package diff
 
 import (
-	"bytes"
-	"encoding/json"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"sort"
 
-	"github.com/mickaelpham/znt/auth"
-	"github.com/spf13/viper"
+	"github.com/mickaelpham/znt/zuora"
 )
 
-type triggersResponse struct {
-	Data []Trigger
-	Next string
-}
-
-type notificationsResponse struct {
-	Data []Notification
-	Next string
-}
-
-func fetchTriggers() []Trigger {
-	token := auth.NewToken()
-	result := make([]Trigger, 0)
-	queryPaths := []string{"/events/event-triggers"}
-
-	for len(queryPaths) > 0 {
-		// pop the path to query
-		path := queryPaths[0]
-		queryPaths = queryPaths[1:]
-
-		log.Printf("GET %s\n", path)
-		req, err := http.NewRequest("GET", viper.GetString("baseurl")+path, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Authorization", "Bearer "+token.Val)
-
-		response, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer response.Body.Close()
-
-		if response.StatusCode != 200 {
-			body, err := ioutil.ReadAll(response.Body)
-			if err != nil {
-				log.Fatal(err)
-			}
-			log.Fatal(string(body))
-		}
-
-		dec := json.NewDecoder(response.Body)
-		var body triggersResponse
-		if err = dec.Decode(&body); err != nil {
-			log.Fatal(err)
-		}
+func fetchTriggers(c *zuora.Client) ([]Trigger, error) {
+	remote, err := c.ListEventTriggers()
+	if err != nil {
+		return nil, err
+	}
 
-		// append the data to the current results and add the
-		// next path to query if present
-		result = append(result, body.Data...)
-		if body.Next != "" {
-			queryPaths = append(queryPaths, body.Next)
-		}
+	result := make([]Trigger, 0, len(remote))
+	for _, rmt := range remote {
+		result = append(result, triggerFromAPI(rmt))
 	}
 
 	// sort the triggers by name
@@ -73,139 +22,67 @@ func fetchTriggers() []Trigger {
 		return result[i].EventType.Name < result[j].EventType.Name
 	})
 
-	return result
+	return result, nil
 }
 
 // FetchManagedTriggers retrieves all managed triggers from Zuora
-func FetchManagedTriggers() []Trigger {
-	result := make([]Trigger, 0)
+func FetchManagedTriggers(c *zuora.Client) ([]Trigger, error) {
+	triggers, err := fetchTriggers(c)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, rmt := range fetchTriggers() {
+	result := make([]Trigger, 0)
+	for _, rmt := range triggers {
 		if rmt.Description == managedTriggerDescription {
 			result = append(result, rmt)
 		}
 	}
 
-	return result
+	return result, nil
 }
 
 // FetchManagedNotifications retrieves all managed notifications from Zuora
-func FetchManagedNotifications() []Notification {
-	result := make([]Notification, 0)
-
-	for _, rmt := range fetchNotifications() {
-		if rmt.Description == managedNotificationDescription {
-			result = append(result, rmt)
-		}
+func FetchManagedNotifications(c *zuora.Client) ([]Notification, error) {
+	notifications, err := fetchNotifications(c)
+	if err != nil {
+		return nil, err
 	}
 
-	return result
-}
-
-func fetchNotifications() []Notification {
-	token := auth.NewToken()
 	result := make([]Notification, 0)
-	queryPaths := []string{"/notifications/notification-definitions"}
-
-	for len(queryPaths) > 0 {
-		// pop the path to query
-		path := queryPaths[0]
-		queryPaths = queryPaths[1:]
-
-		log.Printf("GET %s\n", path)
-		req, err := http.NewRequest("GET", viper.GetString("baseurl")+path, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Authorization", "Bearer "+token.Val)
-
-		response, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer response.Body.Close()
-
-		if response.StatusCode != 200 {
-			body, err := ioutil.ReadAll(response.Body)
-			if err != nil {
-				log.Fatal(err)
-			}
-			log.Fatal(string(body))
-		}
-
-		dec := json.NewDecoder(response.Body)
-		var body notificationsResponse
-		if err = dec.Decode(&body); err != nil {
-			log.Fatal(err)
-		}
-
-		// append the data to the current results and add the
-		// next path to query if present
-		result = append(result, body.Data...)
-		if body.Next != "" {
-			queryPaths = append(queryPaths, body.Next)
+	for _, rmt := range notifications {
+		if rmt.Description == managedNotificationDescription {
+			result = append(result, rmt)
 		}
 	}
 
-	// sort the triggers by name
-	// sort.Slice(result, func(i, j int) bool {
-	// 	return result[i].EventType.Name < result[j].EventType.Name
-	// })
-
-	return result
-}
-
-type queryPayload struct {
-	QueryString string `json:"queryString"`
-}
-
-type profilesQueryResponse struct {
-	Records []Profile
-	Done    bool
-	Size    int
+	return result, nil
 }
 
-// FetchProfiles returns all communication profiles in the associated Zuora tenant
-func FetchProfiles() map[string]string {
-	token := auth.NewToken()
-	query := queryPayload{"SELECT Id, ProfileName FROM CommunicationProfile"}
-	payload, err := json.Marshal(query)
+func fetchNotifications(c *zuora.Client) ([]Notification, error) {
+	remote, err := c.ListNotificationDefinitions()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", viper.GetString("baseurl")+"/v1/action/query", bytes.NewBuffer(payload))
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+token.Val)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatal(err)
+	result := make([]Notification, 0, len(remote))
+	for _, rmt := range remote {
+		result = append(result, notificationFromAPI(rmt))
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != 200 {
-		body, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-		log.Fatal(string(body))
-	}
-
-	dec := json.NewDecoder(response.Body)
-	var body profilesQueryResponse
-	if err = dec.Decode(&body); err != nil {
-		log.Fatal(err)
-	}
+	return result, nil
+}
 
-	if !body.Done {
-		log.Fatalln("there are more communication profile to query")
+// profileIDsByName maps the IDs of the communication profiles by name, sorted,
+// several profiles may share a name
+func profileIDsByName(profiles []CommunicationProfile) map[string][]string {
+	result := make(map[string][]string)
+	for _, p := range profiles {
+		result[p.Name] = append(result[p.Name], p.ID)
 	}
 
-	result := make(map[string]string)
-	for _, p := range body.Records {
-		result[p.Name] = p.ID
+	for _, IDs := range result {
+		sort.Strings(IDs)
 	}
 
 	return result
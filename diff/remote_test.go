@@ -0,0 +1,31 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProfileIDsByName(t *testing.T) {
+	t.Run("maps the profile IDs by name", func(t *testing.T) {
+		got := profileIDsByName([]CommunicationProfile{
+			{ID: "1", Name: "A"},
+			{ID: "2", Name: "B"},
+		})
+
+		if want := map[string][]string{"A": {"1"}, "B": {"2"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+
+	t.Run("keeps the profiles sharing a name", func(t *testing.T) {
+		got := profileIDsByName([]CommunicationProfile{
+			{ID: "3", Name: "A"},
+			{ID: "1", Name: "A"},
+			{ID: "2", Name: "B"},
+		})
+
+		if want := map[string][]string{"A": {"1", "3"}, "B": {"2"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
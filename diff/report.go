@@ -0,0 +1,307 @@
+package diff
+
+import (
+	"sort"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// redacted replaces the callout passwords in the reports
+const redacted = "********"
+
+// Report is the machine-readable form of a plan, its JSON encoding is stable
+type Report struct {
+	Triggers        TriggerReport                `json:"triggers"`
+	Notifications   NotificationReport           `json:"notifications"`
+	EmailTemplates  EmailTemplateReport          `json:"emailTemplates"`
+	EventTypes      EventTypeReport              `json:"eventTypes"`
+	ScheduledEvents ScheduledEventReport         `json:"scheduledEvents"`
+	Profiles        []zuora.CommunicationProfile `json:"profiles"`
+	Summary         Summary                      `json:"summary"`
+
+	CommunicationProfiles CommunicationProfileReport `json:"communicationProfiles"`
+
+	// CalloutOverrides describes the overridden callout fields by event type name
+	CalloutOverrides map[string]string `json:"calloutOverrides"`
+
+	// ProfileSelections lists the resolved profiles along with their rule
+	ProfileSelections []ProfileSelection `json:"profileSelections"`
+}
+
+// TriggerUpdateReport is a trigger to update along with its changed fields
+type TriggerUpdateReport struct {
+	Remote   zuora.EventTrigger `json:"remote"`
+	Template zuora.EventTrigger `json:"template"`
+	Fields   []string           `json:"fields"`
+}
+
+// TriggerReport lists the trigger changes
+type TriggerReport struct {
+	Add    []zuora.EventTrigger  `json:"add"`
+	Remove []zuora.EventTrigger  `json:"remove"`
+	Update []TriggerUpdateReport `json:"update"`
+}
+
+// NotificationUpdateReport is a notification to update along with its changed fields
+type NotificationUpdateReport struct {
+	Remote   zuora.NotificationDefinition `json:"remote"`
+	Template zuora.NotificationDefinition `json:"template"`
+	Fields   []string                     `json:"fields"`
+}
+
+// NotificationReport lists the notification changes
+type NotificationReport struct {
+	Add    []zuora.NotificationDefinition `json:"add"`
+	Remove []zuora.NotificationDefinition `json:"remove"`
+	Update []NotificationUpdateReport     `json:"update"`
+}
+
+// EmailTemplateUpdateReport is an email template to update along with its changed fields
+type EmailTemplateUpdateReport struct {
+	Remote   zuora.EmailTemplate `json:"remote"`
+	Template zuora.EmailTemplate `json:"template"`
+	Fields   []string            `json:"fields"`
+}
+
+// EmailTemplateReport lists the email template changes
+type EmailTemplateReport struct {
+	Add    []zuora.EmailTemplate       `json:"add"`
+	Remove []zuora.EmailTemplate       `json:"remove"`
+	Update []EmailTemplateUpdateReport `json:"update"`
+}
+
+// EventTypeUpdateReport is a custom event type to update along with its changed fields
+type EventTypeUpdateReport struct {
+	Remote   zuora.CustomEventType `json:"remote"`
+	Template zuora.CustomEventType `json:"template"`
+	Fields   []string              `json:"fields"`
+}
+
+// EventTypeReport lists the custom event type changes
+type EventTypeReport struct {
+	Add    []zuora.CustomEventType `json:"add"`
+	Remove []zuora.CustomEventType `json:"remove"`
+	Update []EventTypeUpdateReport `json:"update"`
+}
+
+// ScheduledEventUpdateReport is a scheduled event to update along with its changed fields
+type ScheduledEventUpdateReport struct {
+	Remote   zuora.ScheduledEvent `json:"remote"`
+	Template zuora.ScheduledEvent `json:"template"`
+	Fields   []string             `json:"fields"`
+}
+
+// ScheduledEventReport lists the scheduled event changes
+type ScheduledEventReport struct {
+	Add    []zuora.ScheduledEvent       `json:"add"`
+	Remove []zuora.ScheduledEvent       `json:"remove"`
+	Update []ScheduledEventUpdateReport `json:"update"`
+}
+
+// CommunicationProfileUpdateReport is a communication profile to update or adopt
+type CommunicationProfileUpdateReport struct {
+	Remote   zuora.CommunicationProfile `json:"remote"`
+	Template zuora.CommunicationProfile `json:"template"`
+}
+
+// CommunicationProfileReport lists the communication profile changes
+type CommunicationProfileReport struct {
+	Add    []zuora.CommunicationProfile       `json:"add"`
+	Remove []zuora.CommunicationProfile       `json:"remove"`
+	Update []CommunicationProfileUpdateReport `json:"update"`
+}
+
+// Counts of the changes for one resource type
+type Counts struct {
+	Add    int `json:"add"`
+	Remove int `json:"remove"`
+	Update int `json:"update"`
+}
+
+// Summary counts the changes for each resource type
+type Summary struct {
+	Triggers        Counts `json:"triggers"`
+	Notifications   Counts `json:"notifications"`
+	EmailTemplates  Counts `json:"emailTemplates"`
+	EventTypes      Counts `json:"eventTypes"`
+	ScheduledEvents Counts `json:"scheduledEvents"`
+
+	CommunicationProfiles Counts `json:"communicationProfiles"`
+}
+
+// NewReport returns the report of the plan, the profiles are sorted by name
+func NewReport(p *Plan, profiles map[string][]string) Report {
+	result := Report{
+		Triggers: TriggerReport{
+			Add:    make([]zuora.EventTrigger, 0, len(p.Triggers.Add)),
+			Remove: make([]zuora.EventTrigger, 0, len(p.Triggers.Remove)),
+			Update: make([]TriggerUpdateReport, 0, len(p.Triggers.Update)),
+		},
+		Notifications: NotificationReport{
+			Add:    make([]zuora.NotificationDefinition, 0, len(p.Notifications.Add)),
+			Remove: make([]zuora.NotificationDefinition, 0, len(p.Notifications.Remove)),
+			Update: make([]NotificationUpdateReport, 0, len(p.Notifications.Update)),
+		},
+		EmailTemplates: EmailTemplateReport{
+			Add:    make([]zuora.EmailTemplate, 0, len(p.EmailTemplates.Add)),
+			Remove: make([]zuora.EmailTemplate, 0, len(p.EmailTemplates.Remove)),
+			Update: make([]EmailTemplateUpdateReport, 0, len(p.EmailTemplates.Update)),
+		},
+		EventTypes: EventTypeReport{
+			Add:    make([]zuora.CustomEventType, 0, len(p.EventTypes.Add)),
+			Remove: make([]zuora.CustomEventType, 0, len(p.EventTypes.Remove)),
+			Update: make([]EventTypeUpdateReport, 0, len(p.EventTypes.Update)),
+		},
+		ScheduledEvents: ScheduledEventReport{
+			Add:    make([]zuora.ScheduledEvent, 0, len(p.ScheduledEvents.Add)),
+			Remove: make([]zuora.ScheduledEvent, 0, len(p.ScheduledEvents.Remove)),
+			Update: make([]ScheduledEventUpdateReport, 0, len(p.ScheduledEvents.Update)),
+		},
+		CommunicationProfiles: CommunicationProfileReport{
+			Add:    make([]zuora.CommunicationProfile, 0, len(p.CommunicationProfiles.Add)),
+			Remove: make([]zuora.CommunicationProfile, 0, len(p.CommunicationProfiles.Remove)),
+			Update: make([]CommunicationProfileUpdateReport, 0, len(p.CommunicationProfiles.Update)),
+		},
+		Profiles:          make([]zuora.CommunicationProfile, 0, len(profiles)),
+		CalloutOverrides:  make(map[string]string),
+		ProfileSelections: make([]ProfileSelection, 0),
+	}
+
+	for _, t := range p.Triggers.Add {
+		result.Triggers.Add = append(result.Triggers.Add, t.toAPI())
+	}
+	for _, t := range p.Triggers.Remove {
+		result.Triggers.Remove = append(result.Triggers.Remove, t.toAPI())
+	}
+	for _, u := range p.Triggers.Update {
+		result.Triggers.Update = append(result.Triggers.Update, TriggerUpdateReport{
+			Remote:   u.Remote.toAPI(),
+			Template: u.Template.toAPI(),
+			Fields:   u.Fields,
+		})
+	}
+
+	for _, n := range p.Notifications.Add {
+		result.Notifications.Add = append(result.Notifications.Add, n.report())
+	}
+	for _, n := range p.Notifications.Remove {
+		result.Notifications.Remove = append(result.Notifications.Remove, n.report())
+	}
+	for _, u := range p.Notifications.Update {
+		result.Notifications.Update = append(result.Notifications.Update, NotificationUpdateReport{
+			Remote:   u.Remote.report(),
+			Template: u.Template.report(),
+			Fields:   u.Fields,
+		})
+	}
+
+	for _, e := range p.EmailTemplates.Add {
+		result.EmailTemplates.Add = append(result.EmailTemplates.Add, e.toAPI())
+	}
+	for _, e := range p.EmailTemplates.Remove {
+		result.EmailTemplates.Remove = append(result.EmailTemplates.Remove, e.toAPI())
+	}
+	for _, u := range p.EmailTemplates.Update {
+		result.EmailTemplates.Update = append(result.EmailTemplates.Update, EmailTemplateUpdateReport{
+			Remote:   u.Remote.toAPI(),
+			Template: u.Template.toAPI(),
+			Fields:   u.Fields,
+		})
+	}
+
+	for _, e := range p.EventTypes.Add {
+		result.EventTypes.Add = append(result.EventTypes.Add, e.toAPI())
+	}
+	for _, e := range p.EventTypes.Remove {
+		result.EventTypes.Remove = append(result.EventTypes.Remove, e.toAPI())
+	}
+	for _, u := range p.EventTypes.Update {
+		result.EventTypes.Update = append(result.EventTypes.Update, EventTypeUpdateReport{
+			Remote:   u.Remote.toAPI(),
+			Template: u.Template.toAPI(),
+			Fields:   u.Fields,
+		})
+	}
+
+	for _, s := range p.ScheduledEvents.Add {
+		result.ScheduledEvents.Add = append(result.ScheduledEvents.Add, s.toAPI())
+	}
+	for _, s := range p.ScheduledEvents.Remove {
+		result.ScheduledEvents.Remove = append(result.ScheduledEvents.Remove, s.toAPI())
+	}
+	for _, u := range p.ScheduledEvents.Update {
+		result.ScheduledEvents.Update = append(result.ScheduledEvents.Update, ScheduledEventUpdateReport{
+			Remote:   u.Remote.toAPI(),
+			Template: u.Template.toAPI(),
+			Fields:   u.Fields,
+		})
+	}
+
+	for _, cp := range p.CommunicationProfiles.Add {
+		result.CommunicationProfiles.Add = append(result.CommunicationProfiles.Add, cp.toAPI())
+	}
+	for _, cp := range p.CommunicationProfiles.Remove {
+		result.CommunicationProfiles.Remove = append(result.CommunicationProfiles.Remove, cp.toAPI())
+	}
+	for _, u := range p.CommunicationProfiles.Update {
+		result.CommunicationProfiles.Update = append(result.CommunicationProfiles.Update, CommunicationProfileUpdateReport{
+			Remote:   u.Remote.toAPI(),
+			Template: u.Template.toAPI(),
+		})
+	}
+
+	for name, IDs := range profiles {
+		for _, ID := range IDs {
+			result.Profiles = append(result.Profiles, zuora.CommunicationProfile{ID: ID, ProfileName: name})
+		}
+	}
+	sort.Slice(result.Profiles, func(i, j int) bool {
+		a, b := result.Profiles[i], result.Profiles[j]
+		return a.ProfileName < b.ProfileName || a.ProfileName == b.ProfileName && a.ID < b.ID
+	})
+
+	result.Summary = Summary{
+		Triggers: Counts{
+			Add:    len(result.Triggers.Add),
+			Remove: len(result.Triggers.Remove),
+			Update: len(result.Triggers.Update),
+		},
+		Notifications: Counts{
+			Add:    len(result.Notifications.Add),
+			Remove: len(result.Notifications.Remove),
+			Update: len(result.Notifications.Update),
+		},
+		EmailTemplates: Counts{
+			Add:    len(result.EmailTemplates.Add),
+			Remove: len(result.EmailTemplates.Remove),
+			Update: len(result.EmailTemplates.Update),
+		},
+		EventTypes: Counts{
+			Add:    len(result.EventTypes.Add),
+			Remove: len(result.EventTypes.Remove),
+			Update: len(result.EventTypes.Update),
+		},
+		ScheduledEvents: Counts{
+			Add:    len(result.ScheduledEvents.Add),
+			Remove: len(result.ScheduledEvents.Remove),
+			Update: len(result.ScheduledEvents.Update),
+		},
+		CommunicationProfiles: Counts{
+			Add:    len(result.CommunicationProfiles.Add),
+			Remove: len(result.CommunicationProfiles.Remove),
+			Update: len(result.CommunicationProfiles.Update),
+		},
+	}
+
+	return result
+}
+
+// report returns the notification definition without its callout password
+func (n Notification) report() zuora.NotificationDefinition {
+	result := n.toAPI()
+	if result.Callout != nil && result.Callout.CalloutAuth.Password != "" {
+		result.Callout.CalloutAuth.Password = redacted
+	}
+
+	return result
+}
@@ -0,0 +1,41 @@
+package diff
+
+import "testing"
+
+func TestReport(t *testing.T) {
+	plan := &Plan{
+		Triggers: TriggerDiff{
+			Add: []Trigger{NewTrigger("Account", "insert", "changeType == 'INSERT'")},
+		},
+		Notifications: NotificationDiff{
+			Add: []Notification{
+				{
+					CalloutActive:          true,
+					CommunicationProfileID: "profile-id-123",
+					EventTypeName:          "znt-Account-onInsert",
+					Callout: Callout{
+						CalloutAuth: CalloutAuth{Password: "verysecret"},
+					},
+				},
+			},
+		},
+	}
+
+	got := NewReport(plan, map[string][]string{"Profile B": {"profile-id-456"}, "Profile A": {"profile-id-123"}})
+
+	if got.Summary.Triggers.Add != 1 || got.Summary.Notifications.Add != 1 || got.Summary.Notifications.Remove != 0 {
+		t.Errorf("Summary: got %+v", got.Summary)
+	}
+
+	if password := got.Notifications.Add[0].Callout.CalloutAuth.Password; password != redacted {
+		t.Errorf("Password: got %q want %q", password, redacted)
+	}
+
+	if plan.Notifications.Add[0].Callout.CalloutAuth.Password != "verysecret" {
+		t.Errorf("the plan password was redacted")
+	}
+
+	if got.Profiles[0].ProfileName != "Profile A" || got.Profiles[1].ProfileName != "Profile B" {
+		t.Errorf("Profiles: got %v want them sorted by name", got.Profiles)
+	}
+}
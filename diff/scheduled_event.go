@@ -0,0 +1,353 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+const managedScheduledEventDescription = "scheduled event managed by znt"
+
+// ScheduledEventParameter is a parameter of the scheduled event condition,
+// valued by the notifications
+type ScheduledEventParameter struct {
+	Description string   `json:"description,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Options     []string `json:"options,omitempty"`
+	ValueType   string   `json:"valueType"`
+}
+
+// ScheduledEvent fires daily at the given time for the records of the base
+// object matching the condition
+type ScheduledEvent struct {
+	ID          string
+	Active      bool
+	BaseObject  string
+	Condition   string
+	Description string
+	DisplayName string
+	Field       string
+	Hours       int
+	Minutes     int
+	Name        string
+	Parameters  map[string]ScheduledEventParameter
+}
+
+// ScheduledEventName returns the name of the scheduled event managed by ZNT
+func ScheduledEventName(baseObject, name string) string {
+	return "znt-" + baseObject + "-" + name
+}
+
+// NewScheduledEvent managed by ZNT
+func NewScheduledEvent(s ScheduledEventTemplate) ScheduledEvent {
+	name := ScheduledEventName(s.BaseObject, s.Name)
+
+	displayName := s.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+
+	return ScheduledEvent{
+		Active:      true,
+		BaseObject:  s.BaseObject,
+		Condition:   s.Condition,
+		Description: managedScheduledEventDescription,
+		DisplayName: displayName,
+		Field:       s.Field,
+		Hours:       s.Hours,
+		Minutes:     s.Minutes,
+		Name:        name,
+		Parameters:  s.Parameters,
+	}
+}
+
+// ScheduledEventDefinitions expected from the template, sorted by name
+func (t *Template) ScheduledEventDefinitions() []ScheduledEvent {
+	result := make([]ScheduledEvent, 0, len(t.ScheduledEvents))
+	for _, s := range t.ScheduledEvents {
+		result = append(result, NewScheduledEvent(s))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func (s ScheduledEvent) String() string {
+	return fmt.Sprintf("%s {%s.%s at %02d:%02d}", s.Name, s.BaseObject, s.Field, s.Hours, s.Minutes)
+}
+
+// Changes lists the fields of the remote scheduled event which differ from
+// this scheduled event
+func (s ScheduledEvent) Changes(remote ScheduledEvent) []string {
+	result := make([]string, 0)
+
+	// the template always expects the scheduled event to be active
+	if !remote.Active {
+		result = append(result, "Active")
+	}
+
+	if s.Condition != remote.Condition {
+		result = append(result, "Condition")
+	}
+
+	if s.DisplayName != remote.DisplayName {
+		result = append(result, "DisplayName")
+	}
+
+	if s.Field != remote.Field {
+		result = append(result, "Field")
+	}
+
+	if s.Hours != remote.Hours || s.Minutes != remote.Minutes {
+		result = append(result, "Schedule")
+	}
+
+	if (len(s.Parameters) > 0 || len(remote.Parameters) > 0) && !reflect.DeepEqual(s.Parameters, remote.Parameters) {
+		result = append(result, "Parameters")
+	}
+
+	return result
+}
+
+func scheduledEventFromAPI(s zuora.ScheduledEvent) ScheduledEvent {
+	result := ScheduledEvent{
+		ID:          s.ID,
+		Active:      s.Active,
+		BaseObject:  s.APIObject,
+		Condition:   s.Condition,
+		Description: s.Description,
+		DisplayName: s.DisplayName,
+		Field:       s.APIField,
+		Hours:       s.Hours,
+		Minutes:     s.Minutes,
+		Name:        s.Name,
+	}
+
+	if len(s.Parameters) > 0 {
+		result.Parameters = make(map[string]ScheduledEventParameter, len(s.Parameters))
+		for name, p := range s.Parameters {
+			result.Parameters[name] = ScheduledEventParameter(p)
+		}
+	}
+
+	return result
+}
+
+func (s ScheduledEvent) toAPI() zuora.ScheduledEvent {
+	result := zuora.ScheduledEvent{
+		ID:          s.ID,
+		Active:      s.Active,
+		APIField:    s.Field,
+		APIObject:   s.BaseObject,
+		Condition:   s.Condition,
+		Description: s.Description,
+		DisplayName: s.DisplayName,
+		Hours:       s.Hours,
+		Minutes:     s.Minutes,
+		Name:        s.Name,
+	}
+
+	if len(s.Parameters) > 0 {
+		result.Parameters = make(map[string]zuora.ScheduledEventParameter, len(s.Parameters))
+		for name, p := range s.Parameters {
+			result.Parameters[name] = zuora.ScheduledEventParameter(p)
+		}
+	}
+
+	return result
+}
+
+// FetchManagedScheduledEvents retrieves all managed scheduled events from Zuora
+func FetchManagedScheduledEvents(c *zuora.Client) ([]ScheduledEvent, error) {
+	remote, err := c.ListScheduledEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ScheduledEvent, 0)
+	for _, rmt := range remote {
+		if rmt.Description == managedScheduledEventDescription {
+			result = append(result, scheduledEventFromAPI(rmt))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Insert the scheduled event in the targeted Zuora environment
+func (s ScheduledEvent) Insert(c *zuora.Client) error {
+	if _, err := c.CreateScheduledEvent(s.toAPI()); err != nil {
+		return fmt.Errorf("creating scheduled event %s: %w", s, err)
+	}
+
+	return nil
+}
+
+// Update the scheduled event in place in the targeted Zuora environment
+func (s ScheduledEvent) Update(c *zuora.Client) error {
+	if _, err := c.UpdateScheduledEvent(s.ID, s.toAPI()); err != nil {
+		return fmt.Errorf("updating scheduled event %s: %w", s, err)
+	}
+
+	return nil
+}
+
+// Destroy the scheduled event in the targeted Zuora environment
+func (s ScheduledEvent) Destroy(c *zuora.Client) error {
+	if err := c.DeleteScheduledEvent(s.ID); err != nil {
+		return fmt.Errorf("deleting scheduled event %s: %w", s, err)
+	}
+
+	return nil
+}
+
+// ScheduledEventUpdate is a remote scheduled event which differs from the template
+type ScheduledEventUpdate struct {
+	Remote   ScheduledEvent
+	Template ScheduledEvent
+	Fields   []string
+}
+
+// Activation returns true when the only change is the scheduled event reactivation
+func (u ScheduledEventUpdate) Activation() bool {
+	return len(u.Fields) == 1 && u.Fields[0] == "Active"
+}
+
+func (u ScheduledEventUpdate) String() string {
+	if u.Activation() {
+		return u.Remote.String() + " (activated)"
+	}
+
+	return u.Remote.String() + " (changed: " + strings.Join(u.Fields, ", ") + ")"
+}
+
+// ScheduledEventDiff contains the differences between the template and the remote environment
+type ScheduledEventDiff struct {
+	Add    []ScheduledEvent
+	Remove []ScheduledEvent
+	Update []ScheduledEventUpdate
+}
+
+// NewScheduledEventDiff accepts scheduled events sorted by name and return the diff
+func NewScheduledEventDiff(template, remote []ScheduledEvent) ScheduledEventDiff {
+	result := ScheduledEventDiff{}
+
+	i := 0
+	j := 0
+
+	for i < len(template) && j < len(remote) {
+		if template[i].Name == remote[j].Name {
+			if fields := template[i].Changes(remote[j]); len(fields) > 0 {
+				result.Update = append(result.Update, ScheduledEventUpdate{
+					Remote:   remote[j],
+					Template: template[i],
+					Fields:   fields,
+				})
+			}
+			i++
+			j++
+		} else if template[i].Name < remote[j].Name {
+			result.Add = append(result.Add, template[i])
+			i++
+		} else {
+			result.Remove = append(result.Remove, remote[j])
+			j++
+		}
+	}
+
+	// remaining elements of a need to be added
+	for i < len(template) {
+		result.Add = append(result.Add, template[i])
+		i++
+	}
+
+	// remaining elements of remote need to be removed
+	for j < len(remote) {
+		result.Remove = append(result.Remove, remote[j])
+		j++
+	}
+
+	return result
+}
+
+func (d ScheduledEventDiff) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n--- Scheduled Event Diff\n\n")
+
+	if len(d.Add) > 0 {
+		sb.WriteString("These scheduled events will be created: \n")
+		for _, s := range d.Add {
+			sb.WriteString("  * " + s.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Remove) > 0 {
+		sb.WriteString("These scheduled events will be deleted: \n")
+		for _, s := range d.Remove {
+			sb.WriteString("  * " + s.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(d.Update) > 0 {
+		sb.WriteString("These scheduled events will be updated: \n")
+		for _, u := range d.Update {
+			sb.WriteString("  * " + u.String() + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// ApplyAdd creates the missing scheduled events and updates the changed
+// ones, it must run before the notifications targeting them are created. A
+// failed activation is returned as an ActivationError once the others are updated
+func (d ScheduledEventDiff) ApplyAdd(c *zuora.Client) error {
+	for _, s := range d.Add {
+		if err := s.Insert(c); err != nil {
+			return err
+		}
+	}
+
+	var activated activations
+	for _, u := range d.Update {
+		s := u.Template
+		s.ID = u.Remote.ID
+		err := s.Update(c)
+		if u.Template.Active && !u.Remote.Active {
+			activated.report("scheduled event "+u.Remote.String(), err)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return activated.err()
+}
+
+// ApplyRemove deletes the scheduled events no longer in the template, it
+// must run once the notifications no longer target them
+func (d ScheduledEventDiff) ApplyRemove(c *zuora.Client) error {
+	for _, s := range d.Remove {
+		if err := s.Destroy(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScheduledEvents(t *testing.T) {
+	days := map[string]ScheduledEventParameter{
+		"days": {DisplayName: "Days before due", ValueType: "INTEGER"},
+	}
+
+	tpl := Template{
+		Profiles: []string{"Profile A"},
+		ScheduledEvents: []ScheduledEventTemplate{
+			{
+				Name:       "dueSoon",
+				BaseObject: "Invoice",
+				Field:      "DueDate",
+				Hours:      9,
+				Condition:  "Invoice.Status = 'Posted' AND Invoice.Balance > 0 AND Invoice.DueDate = TODAY + {{days}}",
+				Parameters: days,
+			},
+		},
+		Notifications: []NotificationTemplate{
+			{
+				BaseObject:           "Invoice",
+				ScheduledEvent:       "dueSoon",
+				ScheduledEventParams: map[string]string{"days": "3"},
+			},
+		},
+	}
+
+	t.Run("maps the template to managed scheduled events", func(t *testing.T) {
+		got := tpl.ScheduledEventDefinitions()
+		want := []ScheduledEvent{{
+			Active:      true,
+			BaseObject:  "Invoice",
+			Condition:   "Invoice.Status = 'Posted' AND Invoice.Balance > 0 AND Invoice.DueDate = TODAY + {{days}}",
+			Description: managedScheduledEventDescription,
+			DisplayName: "znt-Invoice-dueSoon",
+			Field:       "DueDate",
+			Hours:       9,
+			Name:        "znt-Invoice-dueSoon",
+			Parameters:  days,
+		}}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+
+		if roundtrip := scheduledEventFromAPI(got[0].toAPI()); !reflect.DeepEqual(roundtrip, got[0]) {
+			t.Errorf("got %+v after the API roundtrip", roundtrip)
+		}
+	})
+
+	t.Run("notifies on the scheduled event", func(t *testing.T) {
+		got, err := tpl.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 1 || got[0].EventTypeName != "znt-Invoice-dueSoon" || got[0].FilterRuleParams["days"] != "3" {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("rejects an undeclared scheduled event", func(t *testing.T) {
+		invalid := tpl
+		invalid.Notifications = []NotificationTemplate{{BaseObject: "Account", ScheduledEvent: "dueSoon"}}
+
+		if _, err := invalid.NotificationDefinitions(map[string][]string{"Profile A": {"p1"}}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("diffs the scheduled events by name", func(t *testing.T) {
+		remote := []ScheduledEvent{
+			{ID: "1", Active: true, BaseObject: "Account", Name: "znt-Account-renewal"},
+			{ID: "2", Active: true, BaseObject: "Invoice", Field: "DueDate", Hours: 8, Name: "znt-Invoice-dueSoon", DisplayName: "znt-Invoice-dueSoon",
+				Condition: tpl.ScheduledEvents[0].Condition, Parameters: days},
+		}
+
+		got := NewScheduledEventDiff(tpl.ScheduledEventDefinitions(), remote)
+
+		if len(got.Add) != 0 || len(got.Remove) != 1 || got.Remove[0].ID != "1" {
+			t.Errorf("got add %v and remove %v", got.Add, got.Remove)
+		}
+
+		if len(got.Update) != 1 || !reflect.DeepEqual(got.Update[0].Fields, []string{"Schedule"}) {
+			t.Errorf("got update %v", got.Update)
+		}
+	})
+
+	t.Run("updates the parameters of the notifications", func(t *testing.T) {
+		template := Notification{Active: true, FilterRuleParams: map[string]string{"days": "3"}}
+		remote := Notification{Active: true, FilterRuleParams: map[string]string{"days": "5"}}
+
+		if got := template.Changes(remote); !reflect.DeepEqual(got, []string{"FilterRuleParams"}) {
+			t.Errorf("got changes %v", got)
+		}
+	})
+}
@@ -1,35 +1,320 @@
 package diff
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"strings"
 )
 
 // Template represents the intended state
 type Template struct {
-	Callout Callout
+	// Include lists the template files, or directories, merged into this
+	// template; the paths are relative to the including file
+	Include []string `json:"include,omitempty"`
 
-	Profiles []string
+	Callout Callout `json:"callout"`
 
-	Notifications []struct {
-		BaseObject string
-		Triggers   []struct {
-			Name      string
-			Condition string
+	Profiles []string `json:"profiles"`
+
+	Notifications []NotificationTemplate `json:"notifications"`
+
+	EmailTemplates []EmailTemplateTemplate `json:"emailTemplates,omitempty"`
+
+	EventTypes []EventTypeTemplate `json:"eventTypes,omitempty"`
+
+	ScheduledEvents []ScheduledEventTemplate `json:"scheduledEvents,omitempty"`
+
+	CommunicationProfiles []CommunicationProfileTemplate `json:"communicationProfiles,omitempty"`
+}
+
+// NotificationTemplate declares the triggers of a base object, and the
+// params of the callout sent when they fire
+type NotificationTemplate struct {
+	BaseObject    string            `json:"baseObject"`
+	Triggers      []TriggerTemplate `json:"triggers"`
+	CalloutParams map[string]string `json:"calloutParams,omitempty"`
+
+	// EventType is the name of a custom event type declared in the template,
+	// the notification fires when it is published rather than on triggers
+	EventType string `json:"eventType,omitempty"`
+
+	// ScheduledEvent is the name of a scheduled event of the base object
+	// declared in the template, the notification fires when it is scheduled
+	// rather than on triggers. ScheduledEventParams values its parameters.
+	ScheduledEvent       string            `json:"scheduledEvent,omitempty"`
+	ScheduledEventParams map[string]string `json:"scheduledEventParams,omitempty"`
+
+	// Callout overrides the shared callout for all the triggers
+	Callout *CalloutOverride `json:"callout,omitempty"`
+
+	// EmailTemplateID sends an email with the Zuora email template when the
+	// notification fires, EmailActive defaults to true. The callout is not
+	// sent when CalloutActive is false.
+	EmailTemplateID string `json:"emailTemplateId,omitempty"`
+	EmailActive     *bool  `json:"emailActive,omitempty"`
+	CalloutActive   *bool  `json:"calloutActive,omitempty"`
+
+	// EmailTemplate is the name of an email template declared in the
+	// template, it replaces EmailTemplateID
+	EmailTemplate string `json:"emailTemplate,omitempty"`
+
+	// Profiles replaces the top-level profiles for this notification, and
+	// ExcludeProfiles removes some of them
+	Profiles        []string `json:"profiles,omitempty"`
+	ExcludeProfiles []string `json:"excludeProfiles,omitempty"`
+
+	// source is the file declaring the notification
+	source string
+}
+
+// TriggerTemplate is a named condition on the base object
+type TriggerTemplate struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+
+	// Callout overrides the notification callout for this trigger
+	Callout *CalloutOverride `json:"callout,omitempty"`
+}
+
+// EmailTemplateTemplate declares an email template managed by ZNT
+type EmailTemplateTemplate struct {
+	Name          string `json:"name"`
+	EventTypeName string `json:"eventTypeName"`
+	Subject       string `json:"subject"`
+
+	// From and ReplyTo default to the tenant email address
+	From     string `json:"from,omitempty"`
+	FromName string `json:"fromName,omitempty"`
+	ReplyTo  string `json:"replyTo,omitempty"`
+
+	// To is an email address, or a Zuora recipient type (BillToContact by default)
+	To string `json:"to,omitempty"`
+
+	// BodyFile is relative to the declaring template or overlay file, its
+	// content replaces Body
+	BodyFile string `json:"bodyFile,omitempty"`
+	Body     string `json:"body,omitempty"`
+	HTML     bool   `json:"html,omitempty"`
+
+	// source is the file declaring the email template, and bodyDir the
+	// directory its body file is relative to
+	source  string
+	bodyDir string
+}
+
+// EventTypeTemplate declares a custom event type managed by ZNT
+type EventTypeTemplate struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// ScheduledEventTemplate declares a scheduled event of the base object,
+// firing daily at hours:minutes for the records matching the condition
+type ScheduledEventTemplate struct {
+	Name        string `json:"name"`
+	BaseObject  string `json:"baseObject"`
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Field is the date field of the base object the schedule is relative to
+	Field     string `json:"field"`
+	Hours     int    `json:"hours"`
+	Minutes   int    `json:"minutes"`
+	Condition string `json:"condition"`
+
+	Parameters map[string]ScheduledEventParameter `json:"parameters,omitempty"`
+}
+
+// CommunicationProfileTemplate declares a communication profile. A managed
+// profile (the default) is kept in sync with the template, and only deleted
+// when declared with delete, as customer accounts reference it. A profile
+// which already exists is left untouched unless it is adopted, which makes it
+// managed.
+type CommunicationProfileTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Managed     *bool  `json:"managed,omitempty"`
+	Adopt       bool   `json:"adopt,omitempty"`
+	Delete      bool   `json:"delete,omitempty"`
+}
+
+// managed returns false when the profile is only created when missing
+func (p CommunicationProfileTemplate) managed() bool {
+	return p.Managed == nil || *p.Managed
+}
+
+// RedactSecrets hides the callout passwords, before printing the template
+func (t *Template) RedactSecrets() {
+	redact := func(auth *CalloutAuth) {
+		if auth != nil && auth.Password != "" {
+			auth.Password = redacted
+		}
+	}
+
+	redact(&t.Callout.CalloutAuth)
+	for _, n := range t.Notifications {
+		if n.Callout != nil {
+			redact(n.Callout.CalloutAuth)
+		}
+
+		for _, trigger := range n.Triggers {
+			if trigger.Callout != nil {
+				redact(trigger.Callout.CalloutAuth)
+			}
 		}
-		CalloutParams map[string]string
 	}
 }
 
-// Parse the input template file
-func Parse(r io.Reader) (*Template, error) {
-	dec := json.NewDecoder(r)
+// CalloutOverrides describes the overridden callout fields by event type name
+func (t *Template) CalloutOverrides() map[string]string {
+	result := make(map[string]string)
 
-	var template Template
-	err := dec.Decode(&template)
+	for _, n := range t.Notifications {
+		if n.EventType != "" && n.Callout != nil {
+			result[CustomEventTypeName(n.EventType)] = n.Callout.String()
+		}
+
+		if n.ScheduledEvent != "" && n.Callout != nil {
+			result[ScheduledEventName(n.BaseObject, n.ScheduledEvent)] = n.Callout.String()
+		}
+
+		for _, trigger := range n.Triggers {
+			if override := n.Callout.merge(trigger.Callout); override != nil {
+				name := NewTrigger(n.BaseObject, trigger.Name, trigger.Condition).EventType.Name
+				result[name] = override.String()
+			}
+		}
+	}
+
+	return result
+}
+
+// ParseError points at the line and column of the template which failed to parse
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "template"
+	}
+
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", file, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %v", file, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse the input template file, in JSON or YAML depending on its content.
+// Its includes are relative to the working directory.
+func Parse(r io.Reader) (*Template, error) {
+	content, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return &template, nil
+	l := newLoader()
+	if err := l.loadContent("", ".", content, looksLikeJSON(content)); err != nil {
+		return nil, err
+	}
+
+	return l.merge()
+}
+
+// ParseFile parses the template file, in JSON or YAML depending on its
+// extension, or on its content for the other extensions. Given a directory,
+// it merges all the JSON and YAML templates of the directory.
+func ParseFile(path string) (*Template, error) {
+	l := newLoader()
+	if err := l.loadPath(path); err != nil {
+		return nil, err
+	}
+
+	return l.merge()
+}
+
+// looksLikeJSON returns true when the content starts like a JSON object
+func looksLikeJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// parse the template content, also returning its top-level keys in lowercase
+func parse(file string, content []byte, isJSON bool) (*Template, map[string]bool, error) {
+	if isJSON {
+		// report the JSON syntax errors as encoding/json sees them
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			if serr, ok := err.(*json.SyntaxError); ok {
+				line, column := position(content, int(serr.Offset)-1)
+				return nil, nil, &ParseError{File: file, Line: line, Column: column, Err: err}
+			}
+			return nil, nil, &ParseError{File: file, Err: err}
+		}
+	}
+
+	// JSON is valid YAML: both formats are converted to JSON through YAML
+	// nodes, which remember where each value comes from
+	doc, err := toJSON(content)
+	if err != nil {
+		return nil, nil, &ParseError{File: file, Err: err}
+	}
+
+	var template Template
+	if err := json.Unmarshal(doc.content, &template); err != nil {
+		perr := &ParseError{File: file, Err: err}
+		if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			perr.Line, perr.Column = doc.position(int(terr.Offset))
+			perr.Err = fmt.Errorf("cannot use %s as %s for %s", terr.Value, terr.Type, terr.Field)
+		}
+		return nil, nil, perr
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc.content, &fields); err != nil {
+		return nil, nil, &ParseError{File: file, Err: err}
+	}
+
+	keys := make(map[string]bool)
+	for key := range fields {
+		keys[strings.ToLower(key)] = true
+	}
+
+	for i := range template.Notifications {
+		template.Notifications[i].source = file
+	}
+	for i := range template.EmailTemplates {
+		template.EmailTemplates[i].source = file
+	}
+
+	return &template, keys, nil
+}
+
+// position returns the line and column of the byte at offset
+func position(content []byte, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(content) {
+		offset = len(content)
+	}
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+
+	return line, column
 }
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("YAML and JSON templates are equivalent", func(t *testing.T) {
+		fromJSON, err := Parse(strings.NewReader(`
+{
+  "callout": {
+    "calloutAuth": {"preemptive": true, "username": "janedoe"},
+    "calloutBaseurl": "https://example.com/callout"
+  },
+  "profiles": ["Profile A"],
+  "notifications": [
+    {
+      "baseObject": "Account",
+      "triggers": [{"name": "insert", "condition": "changeType == 'INSERT'"}],
+      "calloutParams": {"AccountName": "<Account.Name>"}
+    }
+  ]
+}
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fromYAML, err := Parse(strings.NewReader(`
+# shared by every notification
+callout:
+  calloutAuth:
+    preemptive: true
+    username: janedoe
+  calloutBaseurl: https://example.com/callout
+profiles:
+  - Profile A
+notifications:
+  - baseObject: Account
+    triggers:
+      # a new account is created
+      - name: insert
+        condition: changeType == 'INSERT'
+    calloutParams:
+      AccountName: <Account.Name>
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(fromJSON, fromYAML) {
+			t.Errorf("got %v want %v", fromYAML, fromJSON)
+		}
+	})
+
+	t.Run("errors point at the line and column", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			line     int
+			column   int
+		}{
+			{"JSON syntax", "{\n  \"profiles\": [\"Profile A\",]\n}", 2, 28},
+			{"JSON type", "{\n  \"profiles\": [\"Profile A\"],\n  \"notifications\": [{\"baseObject\": 42}]\n}", 3, 36},
+			{"YAML type", "profiles:\n  - Profile A\nnotifications:\n  - baseObject:\n      nested: value\n", 5, 7},
+		}
+
+		for _, tt := range tests {
+			_, err := Parse(strings.NewReader(tt.template))
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("%s: got %v want a *ParseError", tt.name, err)
+				continue
+			}
+
+			if perr.Line != tt.line || perr.Column != tt.column {
+				t.Errorf("%s: got %d:%d want %d:%d (%v)", tt.name, perr.Line, perr.Column, tt.line, tt.column, perr)
+			}
+		}
+	})
+
+	t.Run("YAML syntax errors are reported", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("profiles:\n  - Profile A\n - Profile B\n"))
+		if err == nil || !strings.Contains(err.Error(), "line") {
+			t.Errorf("got %v want a YAML syntax error with its line", err)
+		}
+	})
+}
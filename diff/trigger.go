@@ -1,17 +1,11 @@
 package diff
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"sort"
 	"strings"
 
-	"github.com/mickaelpham/znt/auth"
-	"github.com/spf13/viper"
+	"github.com/mickaelpham/znt/zuora"
 )
 
 // EventType fired when the trigger conditions are met
@@ -81,68 +75,98 @@ func (t Trigger) LessThan(another Trigger) bool {
 	return t.BaseObject < another.BaseObject || t.BaseObject == another.BaseObject && t.Condition < another.Condition
 }
 
-// Stringer interface
-func (t Trigger) String() string {
-	return fmt.Sprintf("{%s on %q}", t.BaseObject, t.Condition)
-}
+// Changes lists the fields of the remote trigger which differ from this trigger
+func (t Trigger) Changes(remote Trigger) []string {
+	result := make([]string, 0)
 
-// Insert the trigger in the target Zuora environment
-func (t Trigger) Insert() error {
-	token := auth.NewToken()
-	payload, err := json.Marshal(t)
-	if err != nil {
-		log.Fatal(err)
+	// the template always expects the trigger to be active
+	if !remote.Active {
+		result = append(result, "Active")
 	}
 
-	req, err := http.NewRequest("POST", viper.GetString("baseurl")+"/events/event-triggers", bytes.NewBuffer(payload))
-	if err != nil {
-		log.Fatal(err)
+	for _, field := range []string{"Condition", "Description", "EventType.Description", "EventType.DisplayName"} {
+		if t.field(field) != remote.field(field) {
+			result = append(result, field)
+		}
 	}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+token.Val)
+	return result
+}
 
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatal(err)
+// field returns the value of the updatable field
+func (t Trigger) field(name string) string {
+	switch name {
+	case "Condition":
+		return t.Condition
+	case "Description":
+		return t.Description
+	case "EventType.Description":
+		return t.EventType.Description
+	case "EventType.DisplayName":
+		return t.EventType.DisplayName
+	default:
+		return ""
 	}
-	defer response.Body.Close()
+}
 
-	if response.StatusCode != 201 {
-		body, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-		log.Fatal(string(body))
-	}
+// Stringer interface
+func (t Trigger) String() string {
+	return fmt.Sprintf("{%s on %q}", t.BaseObject, t.Condition)
+}
 
-	return nil
+func triggerFromAPI(t zuora.EventTrigger) Trigger {
+	return Trigger{
+		ID:          t.ID,
+		Active:      t.Active,
+		BaseObject:  t.BaseObject,
+		Condition:   t.Condition,
+		Description: t.Description,
+		EventType: EventType{
+			Description: t.EventType.Description,
+			DisplayName: t.EventType.DisplayName,
+			Name:        t.EventType.Name,
+		},
+	}
 }
 
-// Destroy the trigger in the targeted Zuora environment
-func (t Trigger) Destroy() error {
-	if t.ID == "" {
-		log.Fatalf("trigger %s doesn't have an ID", t)
+func (t Trigger) toAPI() zuora.EventTrigger {
+	return zuora.EventTrigger{
+		ID:          t.ID,
+		Active:      t.Active,
+		BaseObject:  t.BaseObject,
+		Condition:   t.Condition,
+		Description: t.Description,
+		EventType: zuora.EventType{
+			Description: t.EventType.Description,
+			DisplayName: t.EventType.DisplayName,
+			Name:        t.EventType.Name,
+		},
 	}
+}
 
-	token := auth.NewToken()
-	req, err := http.NewRequest("DELETE", viper.GetString("baseurl")+"/events/event-triggers/"+t.ID, nil)
-	if err != nil {
-		log.Fatal(err)
+// Insert the trigger in the target Zuora environment
+func (t Trigger) Insert(c *zuora.Client) error {
+	if _, err := c.CreateEventTrigger(t.toAPI()); err != nil {
+		return fmt.Errorf("creating trigger %s: %w", t, err)
 	}
-	req.Header.Add("Authorization", "Bearer "+token.Val)
 
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatal(err)
+	return nil
+}
+
+// Update the trigger in place in the targeted Zuora environment
+func (t Trigger) Update(c *zuora.Client) error {
+	if _, err := c.UpdateEventTrigger(t.ID, t.toAPI()); err != nil {
+		return fmt.Errorf("updating trigger %s: %w", t, err)
 	}
-	defer response.Body.Close()
 
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		log.Fatal(err)
+	return nil
+}
+
+// Destroy the trigger in the targeted Zuora environment
+func (t Trigger) Destroy(c *zuora.Client) error {
+	if err := c.DeleteEventTrigger(t.ID); err != nil {
+		return fmt.Errorf("deleting trigger %s: %w", t, err)
 	}
-	log.Println(string(body))
 
 	return nil
 }
@@ -1,56 +1,103 @@
 package diff
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickaelpham/znt/zuora"
+)
+
+// TriggerUpdate is a remote trigger which differs from the template
+type TriggerUpdate struct {
+	Remote   Trigger
+	Template Trigger
+	Fields   []string
+}
+
+// Activation returns true when the only change is the trigger reactivation
+func (u TriggerUpdate) Activation() bool {
+	return len(u.Fields) == 1 && u.Fields[0] == "Active"
+}
+
+func (u TriggerUpdate) String() string {
+	if u.Activation() {
+		return u.Remote.String() + " (activated)"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(u.Remote.EventType.Name + " (changed: " + strings.Join(u.Fields, ", ") + ")")
+	for _, field := range u.Fields {
+		if field == "Active" {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("\n      %s: %q -> %q", field, u.Remote.field(field), u.Template.field(field)))
+	}
+
+	return sb.String()
+}
 
 // TriggerDiff contains the differences between the template and the remote environment
 type TriggerDiff struct {
 	Add    []Trigger
 	Remove []Trigger
-	Update []Trigger
+	Update []TriggerUpdate
 }
 
-// NewTriggerDiff accepts sorted trigger arrays and return the diff
+// NewTriggerDiff accepts sorted trigger arrays and return the diff. The
+// triggers are matched by event type name, or by base object and condition
+// when one of them has no name.
 func NewTriggerDiff(template, remote []Trigger) TriggerDiff {
 	result := TriggerDiff{}
 
-	i := 0
-	j := 0
-
-	for i < len(template) && j < len(remote) {
-		if template[i].Equals(remote[j]) {
-			result.Update = append(result.Update, remote[j])
-			i++
-			j++
-		} else if template[i].LessThan(remote[j]) {
-			result.Add = append(result.Add, template[i])
-			i++
-		} else {
-			result.Remove = append(result.Remove, remote[j])
-			j++
+	remoteByName := make(map[string]int)
+	for j, rmt := range remote {
+		if rmt.EventType.Name != "" {
+			remoteByName[rmt.EventType.Name] = j
 		}
 	}
 
-	// remaining elements of a need to be added
-	for i < len(template) {
-		result.Add = append(result.Add, template[i])
-		i++
-	}
+	matched := make([]bool, len(remote))
+	match := func(t Trigger) (int, bool) {
+		if t.EventType.Name != "" {
+			if j, ok := remoteByName[t.EventType.Name]; ok && !matched[j] {
+				return j, true
+			}
+		}
+
+		for j, rmt := range remote {
+			if !matched[j] && (t.EventType.Name == "" || rmt.EventType.Name == "") && t.Equals(rmt) {
+				return j, true
+			}
+		}
 
-	// remaining elements of remote need to be removed
-	for j < len(remote) {
-		result.Remove = append(result.Remove, remote[j])
-		j++
+		return 0, false
 	}
 
-	// filter out the elements in update which are already active
-	tmp := make([]Trigger, 0)
-	for _, needUpdate := range result.Update {
-		if !needUpdate.Active {
-			tmp = append(tmp, needUpdate)
+	for _, t := range template {
+		j, ok := match(t)
+		if !ok {
+			result.Add = append(result.Add, t)
+			continue
+		}
+
+		matched[j] = true
+		if fields := t.Changes(remote[j]); len(fields) > 0 {
+			result.Update = append(result.Update, TriggerUpdate{
+				Remote:   remote[j],
+				Template: t,
+				Fields:   fields,
+			})
 		}
+	}
 
+	// unmatched elements of remote need to be removed
+	for j, rmt := range remote {
+		if !matched[j] {
+			result.Remove = append(result.Remove, rmt)
+		}
 	}
-	result.Update = tmp
 
 	return result
 }
@@ -78,8 +125,8 @@ func (d TriggerDiff) String() string {
 
 	if len(d.Update) > 0 {
 		sb.WriteString("These triggers will be updated: \n")
-		for _, t := range d.Update {
-			sb.WriteString("  * " + t.String() + " (activated)\n")
+		for _, u := range d.Update {
+			sb.WriteString("  * " + u.String() + "\n")
 		}
 		sb.WriteString("\n")
 	}
@@ -88,12 +135,60 @@ func (d TriggerDiff) String() string {
 }
 
 // Apply the trigger diff to the targeted Zuora environment
-func (d TriggerDiff) Apply() {
+func (d TriggerDiff) Apply(c *zuora.Client) error {
+	if err := d.ApplyAdd(c); err != nil {
+		return err
+	}
+
+	if err := d.ApplyUpdate(c); err != nil {
+		return err
+	}
+
+	return d.ApplyRemove(c)
+}
+
+// ApplyAdd creates the triggers missing from the targeted Zuora environment
+func (d TriggerDiff) ApplyAdd(c *zuora.Client) error {
 	for _, t := range d.Add {
-		t.Insert()
+		if err := t.Insert(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyUpdate replaces the changed triggers with their template definition,
+// keeping their ID so the notifications still reference their event type. The
+// inactive triggers are reactivated, a failed activation is reported and
+// returned as an ActivationError once the other triggers are updated
+func (d TriggerDiff) ApplyUpdate(c *zuora.Client) error {
+	var activated activations
+	for _, u := range d.Update {
+		t := u.Template
+		t.ID = u.Remote.ID
+		err := t.Update(c)
+		if u.Template.Active && !u.Remote.Active {
+			activated.report("trigger "+u.Remote.String(), err)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
 	}
 
+	return activated.err()
+}
+
+// ApplyRemove deletes the triggers no longer in the template, it must run
+// once nothing references their event types
+func (d TriggerDiff) ApplyRemove(c *zuora.Client) error {
 	for _, t := range d.Remove {
-		t.Destroy()
+		if err := t.Destroy(c); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
@@ -1,6 +1,10 @@
 package diff
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestTriggerDiff(t *testing.T) {
 	assertEqual := func(got, want TriggerDiff, t *testing.T) {
@@ -89,4 +93,40 @@ func TestTriggerDiff(t *testing.T) {
 
 		assertEqual(got, want, t)
 	})
+
+	t.Run("named triggers are updated in place", func(t *testing.T) {
+		template := []Trigger{
+			NewTrigger("Account", "insert", "changeType == 'INSERT' && Account.Status == 'Active'"),
+			NewTrigger("Account", "update", "changeType == 'UPDATE'"),
+		}
+
+		remote := []Trigger{
+			NewTrigger("Account", "insert", "changeType == 'INSERT'"),
+			NewTrigger("Account", "update", "changeType == 'UPDATE'"),
+		}
+		remote[0].ID = "trigger-1"
+		remote[1].ID = "trigger-2"
+		remote[1].Active = false
+
+		got := NewTriggerDiff(template, remote)
+
+		want := TriggerDiff{
+			Update: []TriggerUpdate{
+				{Remote: remote[0], Template: template[0], Fields: []string{"Condition"}},
+				{Remote: remote[1], Template: template[1], Fields: []string{"Active"}},
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v want %+v", got, want)
+		}
+
+		if got.Update[0].Activation() || !got.Update[1].Activation() {
+			t.Errorf("got activations %v and %v", got.Update[0].Activation(), got.Update[1].Activation())
+		}
+
+		if s := got.Update[0].String(); !strings.Contains(s, `Condition: "changeType == 'INSERT'" -> "changeType == 'INSERT' && Account.Status == 'Active'"`) {
+			t.Errorf("got %s", s)
+		}
+	})
 }
@@ -0,0 +1,167 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonDocument is a template converted to JSON, along with the position in
+// the original template of each value written to the JSON content
+type jsonDocument struct {
+	content []byte
+	offsets []sourceOffset
+}
+
+type sourceOffset struct {
+	offset int
+	line   int
+	column int
+}
+
+// position returns the original line and column of the value which ends at
+// the JSON offset, as reported by encoding/json errors
+func (d *jsonDocument) position(offset int) (int, int) {
+	line, column := 0, 0
+	for _, o := range d.offsets {
+		if o.offset >= offset {
+			break
+		}
+		line, column = o.line, o.column
+	}
+
+	return line, column
+}
+
+// toJSON converts the YAML (or JSON) content to JSON
+func toJSON(content []byte) (*jsonDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	doc := &jsonDocument{}
+	var buf bytes.Buffer
+
+	if len(root.Content) == 0 {
+		buf.WriteString("{}")
+	} else if err := doc.write(&buf, root.Content[0]); err != nil {
+		return nil, err
+	}
+
+	doc.content = buf.Bytes()
+	return doc, nil
+}
+
+func (d *jsonDocument) write(buf *bytes.Buffer, node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		return d.write(buf, node.Alias)
+	}
+
+	d.offsets = append(d.offsets, sourceOffset{buf.Len(), node.Line, node.Column})
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		first := true
+		for _, pair := range mappingPairs(node) {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+
+			key, _ := json.Marshal(pair[0].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := d.write(buf, pair[1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := d.write(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case yaml.ScalarNode:
+		var v interface{} = node.Value
+		if node.Tag != "!!str" {
+			if err := node.Decode(&v); err != nil {
+				return fmt.Errorf("line %d: %w", node.Line, err)
+			}
+		}
+
+		scalar, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		buf.Write(scalar)
+
+	default:
+		return fmt.Errorf("line %d: unexpected YAML node", node.Line)
+	}
+
+	return nil
+}
+
+// mappingPairs returns the key/value pairs of the mapping, expanding the
+// "<<" merge keys; the keys defined in the mapping win over the merged ones
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	result := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	index := make(map[string]int)
+
+	add := func(key, value *yaml.Node, override bool) {
+		if i, ok := index[key.Value]; ok {
+			if override {
+				result[i][1] = value
+			}
+			return
+		}
+		index[key.Value] = len(result)
+		result = append(result, [2]*yaml.Node{key, value})
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			add(key, value, true)
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			continue
+		}
+
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+
+		for _, m := range merged {
+			if m.Kind == yaml.AliasNode {
+				m = m.Alias
+			}
+			for _, pair := range mappingPairs(m) {
+				add(pair[0], pair[1], false)
+			}
+		}
+	}
+
+	return result
+}
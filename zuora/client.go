@@ -0,0 +1,165 @@
+package zuora
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenSource provides the OAuth token sent with every request
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// TokenInvalidator is implemented by the token sources able to discard a token
+// rejected by Zuora, the client then retries the request once with a new token
+type TokenInvalidator interface {
+	Invalidate(token string)
+}
+
+// Client calls the Zuora REST API of a single tenant
+type Client struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	Tokens     TokenSource
+	Retry      RetryPolicy
+
+	// Log receives one line per request, nothing is logged when nil
+	Log *log.Logger
+}
+
+// NewClient returns a client for the Zuora tenant at baseURL
+func NewClient(baseURL string, tokens TokenSource) *Client {
+	return &Client{
+		HTTPClient: http.DefaultClient,
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Tokens:     tokens,
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.Log != nil {
+		c.Log.Printf(format, v...)
+	}
+}
+
+// sleep is replaced in the tests
+var sleep = time.Sleep
+
+// do sends the request with the JSON encoded payload (if any) and decodes the
+// JSON response into out (if not nil). Only idempotent methods are retried.
+func (c *Client) do(method, path string, payload, out interface{}) error {
+	return c.send(method, path, payload, out, idempotent(method))
+}
+
+// send is do with an explicit retry decision, for the read-only POST requests
+func (c *Client) send(method, path string, payload, out interface{}, retry bool) error {
+	var body []byte
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.roundTrip(method, path, body)
+
+		canRetry := retry && attempt < c.Retry.MaxRetries
+		if err != nil {
+			if _, isZuora := err.(*Error); isZuora || !canRetry {
+				return err
+			}
+		} else if retryable(response.StatusCode) && canRetry {
+			response.Body.Close()
+		} else {
+			defer response.Body.Close()
+			return decodeResponse(response, out)
+		}
+
+		wait := c.Retry.delay(response, attempt)
+		if err != nil {
+			c.logf("%s %s failed (%v), retrying in %s\n", method, path, err, wait)
+		} else {
+			c.logf("%s %s returned %d, retrying in %s\n", method, path, response.StatusCode, wait)
+		}
+		sleep(wait)
+	}
+}
+
+// roundTrip sends a single attempt of the request, it is sent again with a
+// new token when Zuora rejects a revoked one
+func (c *Client) roundTrip(method, path string, payload []byte) (*http.Response, error) {
+	response, token, err := c.authorizedRequest(method, path, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidator, ok := c.Tokens.(TokenInvalidator)
+	if !ok || response.StatusCode != http.StatusUnauthorized {
+		return response, nil
+	}
+
+	response.Body.Close()
+	invalidator.Invalidate(token)
+	c.logf("%s %s returned %d, retrying with a new token\n", method, path, response.StatusCode)
+
+	response, _, err = c.authorizedRequest(method, path, payload)
+	return response, err
+}
+
+// authorizedRequest sends the request with the token, which it returns along
+// with the response
+func (c *Client) authorizedRequest(method, path string, payload []byte) (*http.Response, string, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	token, err := c.Tokens.Token()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.logf("%s %s\n", method, path)
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, "", err
+	}
+	if payload != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(req)
+	return response, token, err
+}
+
+// decodeResponse checks the response status and decodes its JSON body into out
+func decodeResponse(response *http.Response, out interface{}) error {
+	if err := CheckResponse(response); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("%s %s: decoding response: %w", response.Request.Method, response.Request.URL.RequestURI(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package zuora
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type staticToken string
+
+func (t staticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+func TestClient(t *testing.T) {
+	t.Run("list event triggers follows the pagination", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+				t.Errorf("Authorization: got %q want %q", got, "Bearer secret")
+			}
+
+			if r.URL.Query().Get("start") == "" {
+				fmt.Fprint(w, `{"data": [{"id": "1"}], "next": "/events/event-triggers?start=1"}`)
+			} else {
+				fmt.Fprint(w, `{"data": [{"id": "2"}]}`)
+			}
+		}))
+		defer server.Close()
+
+		got, err := NewClient(server.URL, staticToken("secret")).ListEventTriggers()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
+			t.Errorf("got %v want triggers 1 and 2", got)
+		}
+	})
+
+	t.Run("errors carry the status and the Zuora reasons", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"reasons": [{"code": 50000040, "message": "invalid condition"}]}`)
+		}))
+		defer server.Close()
+
+		_, err := NewClient(server.URL, staticToken("secret")).CreateEventTrigger(EventTrigger{})
+
+		var zerr *Error
+		if !errors.As(err, &zerr) {
+			t.Fatalf("got %v want *Error", err)
+		}
+
+		if zerr.StatusCode != http.StatusBadRequest || zerr.Method != "POST" || zerr.Path != "/events/event-triggers" {
+			t.Errorf("got %d %s %s", zerr.StatusCode, zerr.Method, zerr.Path)
+		}
+
+		if len(zerr.Reasons) != 1 || zerr.Reasons[0].Message != "invalid condition" {
+			t.Errorf("Reasons: got %v", zerr.Reasons)
+		}
+	})
+
+	t.Run("query profiles follows the query locator", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatal(err)
+			}
+
+			switch r.URL.Path {
+			case "/v1/action/query":
+				fmt.Fprint(w, `{"records": [{"Id": "1", "ProfileName": "A"}], "done": false, "queryLocator": "locator-1"}`)
+			case "/v1/action/queryMore":
+				if payload["queryLocator"] != "locator-1" {
+					t.Errorf("queryLocator: got %q", payload["queryLocator"])
+				}
+				fmt.Fprint(w, `{"records": [{"Id": "2", "ProfileName": "B"}], "done": true}`)
+			default:
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		got, err := NewClient(server.URL, staticToken("secret")).QueryProfiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
+			t.Errorf("got %v want profiles 1 and 2", got)
+		}
+	})
+
+	t.Run("delete requires an ID", func(t *testing.T) {
+		err := NewClient("http://localhost", staticToken("secret")).DeleteEventTrigger("")
+		if err != ErrMissingID {
+			t.Errorf("got %v want %v", err, ErrMissingID)
+		}
+	})
+
+	t.Run("retries once with a new token when the token is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"id": "1"}`)
+		}))
+		defer server.Close()
+
+		tokens := &rotatingTokens{}
+		created, err := NewClient(server.URL, tokens).CreateEventTrigger(EventTrigger{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if created.ID != "1" || !reflect.DeepEqual(tokens.invalidated, []string{"token-1"}) {
+			t.Errorf("got %v after invalidating %v want trigger 1 after invalidating token-1", created, tokens.invalidated)
+		}
+
+		// the new token is rejected as well, the error is returned
+		tokens.n = 5
+		if _, err := NewClient(server.URL, tokens).CreateEventTrigger(EventTrigger{}); err == nil || len(tokens.invalidated) != 2 {
+			t.Errorf("got %v after invalidating %v want an error after a single retry", err, tokens.invalidated)
+		}
+	})
+}
+
+// rotatingTokens hands out a new token once the previous one is invalidated
+type rotatingTokens struct {
+	n           int
+	invalidated []string
+}
+
+func (t *rotatingTokens) Token() (string, error) {
+	if t.n == 0 {
+		t.n = 1
+	}
+	return fmt.Sprintf("token-%d", t.n), nil
+}
+
+func (t *rotatingTokens) Invalidate(token string) {
+	t.invalidated = append(t.invalidated, token)
+	t.n++
+}
@@ -0,0 +1,71 @@
+package zuora
+
+// EmailTemplate is sent by the email notifications
+type EmailTemplate struct {
+	ID                  string `json:"id,omitempty"`
+	Active              bool   `json:"active"`
+	Description         string `json:"description"`
+	EmailBody           string `json:"emailBody"`
+	EmailSubject        string `json:"emailSubject"`
+	EventTypeName       string `json:"eventTypeName"`
+	FromEmailAddress    string `json:"fromEmailAddress,omitempty"`
+	FromEmailType       string `json:"fromEmailType"`
+	FromName            string `json:"fromName,omitempty"`
+	IsHTML              bool   `json:"isHtml"`
+	Name                string `json:"name"`
+	ReplyToEmailAddress string `json:"replyToEmailAddress,omitempty"`
+	ReplyToEmailType    string `json:"replyToEmailType,omitempty"`
+	ToEmailAddress      string `json:"toEmailAddress,omitempty"`
+	ToEmailType         string `json:"toEmailType"`
+}
+
+type emailTemplatesResponse struct {
+	Data []EmailTemplate `json:"data"`
+	Next string          `json:"next"`
+}
+
+const emailTemplatesPath = "/notifications/email-templates"
+
+// ListEmailTemplates returns every email template, following the pagination
+func (c *Client) ListEmailTemplates() ([]EmailTemplate, error) {
+	result := make([]EmailTemplate, 0)
+
+	for path := emailTemplatesPath; path != ""; {
+		var body emailTemplatesResponse
+		if err := c.do("GET", path, nil, &body); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Data...)
+		path = body.Next
+	}
+
+	return result, nil
+}
+
+// CreateEmailTemplate creates the email template and returns it with its ID
+func (c *Client) CreateEmailTemplate(template EmailTemplate) (EmailTemplate, error) {
+	var created EmailTemplate
+	err := c.do("POST", emailTemplatesPath, template, &created)
+	return created, err
+}
+
+// UpdateEmailTemplate replaces the email template with the given ID
+func (c *Client) UpdateEmailTemplate(id string, template EmailTemplate) (EmailTemplate, error) {
+	if id == "" {
+		return EmailTemplate{}, ErrMissingID
+	}
+
+	var updated EmailTemplate
+	err := c.do("PUT", emailTemplatesPath+"/"+id, template, &updated)
+	return updated, err
+}
+
+// DeleteEmailTemplate deletes the email template with the given ID
+func (c *Client) DeleteEmailTemplate(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do("DELETE", emailTemplatesPath+"/"+id, nil, nil)
+}
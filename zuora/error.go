@@ -0,0 +1,69 @@
+package zuora
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Reason explains why Zuora rejected a request
+type Reason struct {
+	Code    json.Number `json:"code"`
+	Message string      `json:"message"`
+}
+
+// Error is returned when Zuora responds with a non-2xx status code
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+
+	// Body is the raw error body returned by Zuora
+	Body string
+
+	// Reasons are decoded from the body when it follows the Zuora format
+	Reasons []Reason
+}
+
+func (e *Error) Error() string {
+	detail := strings.TrimSpace(e.Body)
+	if len(e.Reasons) > 0 {
+		messages := make([]string, 0, len(e.Reasons))
+		for _, r := range e.Reasons {
+			messages = append(messages, r.Message)
+		}
+		detail = strings.Join(messages, "; ")
+	}
+
+	return fmt.Sprintf("zuora: %s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), detail)
+}
+
+// CheckResponse returns an *Error when the response status code is not 2xx
+func CheckResponse(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	e := &Error{StatusCode: response.StatusCode}
+	if response.Request != nil {
+		e.Method = response.Request.Method
+		e.Path = response.Request.URL.RequestURI()
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	e.Body = string(body)
+
+	var decoded struct {
+		Reasons []Reason
+	}
+	if json.Unmarshal(body, &decoded) == nil {
+		e.Reasons = decoded.Reasons
+	}
+
+	return e
+}
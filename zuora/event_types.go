@@ -0,0 +1,63 @@
+package zuora
+
+// CustomEventType is published by the tenant integrations, rather than fired
+// by an event trigger
+type CustomEventType struct {
+	ID          string `json:"id,omitempty"`
+	Active      bool   `json:"active"`
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+type customEventTypesResponse struct {
+	Data []CustomEventType `json:"data"`
+	Next string            `json:"next"`
+}
+
+const eventTypesPath = "/events/event-types"
+
+// ListCustomEventTypes returns every custom event type, following the pagination
+func (c *Client) ListCustomEventTypes() ([]CustomEventType, error) {
+	result := make([]CustomEventType, 0)
+
+	for path := eventTypesPath; path != ""; {
+		var body customEventTypesResponse
+		if err := c.do("GET", path, nil, &body); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Data...)
+		path = body.Next
+	}
+
+	return result, nil
+}
+
+// CreateCustomEventType creates the custom event type and returns it with its ID
+func (c *Client) CreateCustomEventType(eventType CustomEventType) (CustomEventType, error) {
+	var created CustomEventType
+	err := c.do("POST", eventTypesPath, eventType, &created)
+	return created, err
+}
+
+// UpdateCustomEventType replaces the custom event type with the given ID
+func (c *Client) UpdateCustomEventType(id string, eventType CustomEventType) (CustomEventType, error) {
+	if id == "" {
+		return CustomEventType{}, ErrMissingID
+	}
+
+	var updated CustomEventType
+	err := c.do("PUT", eventTypesPath+"/"+id, eventType, &updated)
+	return updated, err
+}
+
+// DeleteCustomEventType deletes the custom event type with the given ID
+func (c *Client) DeleteCustomEventType(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do("DELETE", eventTypesPath+"/"+id, nil, nil)
+}
@@ -0,0 +1,72 @@
+package zuora
+
+import "errors"
+
+// ErrMissingID is returned when updating or deleting a resource without ID
+var ErrMissingID = errors.New("zuora: missing resource ID")
+
+// EventType fired when the trigger conditions are met
+type EventType struct {
+	Description string `json:"description"`
+	DisplayName string `json:"displayName"`
+	Name        string `json:"name"`
+}
+
+// EventTrigger fires its event type when the condition is met on the base object
+type EventTrigger struct {
+	ID          string    `json:"id,omitempty"`
+	Active      bool      `json:"active"`
+	BaseObject  string    `json:"baseObject"`
+	Condition   string    `json:"condition"`
+	Description string    `json:"description"`
+	EventType   EventType `json:"eventType"`
+}
+
+type eventTriggersResponse struct {
+	Data []EventTrigger `json:"data"`
+	Next string         `json:"next"`
+}
+
+// ListEventTriggers returns every event trigger, following the pagination
+func (c *Client) ListEventTriggers() ([]EventTrigger, error) {
+	result := make([]EventTrigger, 0)
+
+	for path := "/events/event-triggers"; path != ""; {
+		var body eventTriggersResponse
+		if err := c.do("GET", path, nil, &body); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Data...)
+		path = body.Next
+	}
+
+	return result, nil
+}
+
+// CreateEventTrigger creates the event trigger and returns it with its ID
+func (c *Client) CreateEventTrigger(trigger EventTrigger) (EventTrigger, error) {
+	var created EventTrigger
+	err := c.do("POST", "/events/event-triggers", trigger, &created)
+	return created, err
+}
+
+// UpdateEventTrigger replaces the event trigger with the given ID
+func (c *Client) UpdateEventTrigger(id string, trigger EventTrigger) (EventTrigger, error) {
+	if id == "" {
+		return EventTrigger{}, ErrMissingID
+	}
+
+	var updated EventTrigger
+	err := c.do("PUT", "/events/event-triggers/"+id, trigger, &updated)
+	return updated, err
+}
+
+// DeleteEventTrigger deletes the event trigger with the given ID
+func (c *Client) DeleteEventTrigger(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do("DELETE", "/events/event-triggers/"+id, nil, nil)
+}
@@ -0,0 +1,92 @@
+package zuora
+
+// CalloutAuth are the credentials sent with a callout
+type CalloutAuth struct {
+	Domain     string `json:"domain"`
+	Password   string `json:"password,omitempty"`
+	Preemptive bool   `json:"preemptive"`
+	Username   string `json:"username"`
+}
+
+// Callout sent by Zuora when a notification fires
+type Callout struct {
+	ID             string            `json:"id,omitempty"`
+	Active         bool              `json:"active"`
+	CalloutAuth    CalloutAuth       `json:"calloutAuth"`
+	CalloutBaseURL string            `json:"calloutBaseurl"`
+	CalloutParams  map[string]string `json:"calloutParams,omitempty"`
+	CalloutRetry   bool              `json:"calloutRetry"`
+	Description    string            `json:"description"`
+	EventTypeName  string            `json:"eventTypeName"`
+	HTTPMethod     string            `json:"httpMethod"`
+	Name           string            `json:"name"`
+	RequiredAuth   bool              `json:"requiredAuth"`
+}
+
+// NotificationDefinition sends a callout and/or an email when its event
+// type is fired for an account of the communication profile
+type NotificationDefinition struct {
+	ID                     string            `json:"id,omitempty"`
+	Active                 bool              `json:"active"`
+	Callout                *Callout          `json:"callout,omitempty"`
+	CalloutActive          bool              `json:"calloutActive"`
+	CommunicationProfileID string            `json:"communicationProfileId"`
+	Description            string            `json:"description"`
+	EmailActive            bool              `json:"emailActive"`
+	EmailTemplateID        string            `json:"emailTemplateId,omitempty"`
+	EventTypeName          string            `json:"eventTypeName"`
+	EventTypeNamespace     string            `json:"eventTypeNamespace,omitempty"`
+	FilterRuleParams       map[string]string `json:"filterRuleParams,omitempty"`
+	Name                   string            `json:"name"`
+}
+
+type notificationDefinitionsResponse struct {
+	Data []NotificationDefinition `json:"data"`
+	Next string                   `json:"next"`
+}
+
+const notificationDefinitionsPath = "/notifications/notification-definitions"
+
+// ListNotificationDefinitions returns every notification definition, following the pagination
+func (c *Client) ListNotificationDefinitions() ([]NotificationDefinition, error) {
+	result := make([]NotificationDefinition, 0)
+
+	for path := notificationDefinitionsPath; path != ""; {
+		var body notificationDefinitionsResponse
+		if err := c.do("GET", path, nil, &body); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Data...)
+		path = body.Next
+	}
+
+	return result, nil
+}
+
+// CreateNotificationDefinition creates the notification definition and returns it with its ID
+func (c *Client) CreateNotificationDefinition(definition NotificationDefinition) (NotificationDefinition, error) {
+	var created NotificationDefinition
+	err := c.do("POST", notificationDefinitionsPath, definition, &created)
+	return created, err
+}
+
+// UpdateNotificationDefinition replaces the notification definition with the given ID
+func (c *Client) UpdateNotificationDefinition(id string, definition NotificationDefinition) (NotificationDefinition, error) {
+	if id == "" {
+		return NotificationDefinition{}, ErrMissingID
+	}
+
+	var updated NotificationDefinition
+	err := c.do("PUT", notificationDefinitionsPath+"/"+id, definition, &updated)
+	return updated, err
+}
+
+// DeleteNotificationDefinition deletes the notification definition with the given ID
+func (c *Client) DeleteNotificationDefinition(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do("DELETE", notificationDefinitionsPath+"/"+id, nil, nil)
+}
@@ -0,0 +1,132 @@
+package zuora
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingQueryLocator is returned when an incomplete ZOQL query result
+// has no query locator to fetch the next records
+var ErrMissingQueryLocator = errors.New("zuora: missing query locator")
+
+// CommunicationProfile is associated to each customer account
+type CommunicationProfile struct {
+	ID          string `json:"Id,omitempty"`
+	Description string `json:"Description,omitempty"`
+	ProfileName string `json:"ProfileName"`
+}
+
+type queryPayload struct {
+	QueryString string `json:"queryString"`
+}
+
+type queryMorePayload struct {
+	QueryLocator string `json:"queryLocator"`
+}
+
+type profilesQueryResponse struct {
+	Records      []CommunicationProfile `json:"records"`
+	Done         bool                   `json:"done"`
+	Size         int                    `json:"size"`
+	QueryLocator string                 `json:"queryLocator"`
+}
+
+// QueryProfiles returns all communication profiles with a ZOQL query,
+// following the query locator until the result set is complete
+func (c *Client) QueryProfiles() ([]CommunicationProfile, error) {
+	result := make([]CommunicationProfile, 0)
+
+	path := "/v1/action/query"
+	var payload interface{} = queryPayload{"SELECT Id, Description, ProfileName FROM CommunicationProfile"}
+
+	for {
+		var body profilesQueryResponse
+		// ZOQL queries are read-only, they can safely be retried
+		if err := c.send("POST", path, payload, &body, true); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Records...)
+
+		if body.Done {
+			return result, nil
+		}
+
+		if body.QueryLocator == "" {
+			return nil, ErrMissingQueryLocator
+		}
+
+		path = "/v1/action/queryMore"
+		payload = queryMorePayload{body.QueryLocator}
+	}
+}
+
+// objectResponse is returned by the CRUD object API, which reports the
+// errors with a 200 status code
+type objectResponse struct {
+	Success bool   `json:"Success"`
+	ID      string `json:"Id"`
+	Errors  []struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	} `json:"Errors"`
+}
+
+func (r objectResponse) err(method, path string) error {
+	if r.Success {
+		return nil
+	}
+
+	messages := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		messages = append(messages, e.Code+": "+e.Message)
+	}
+
+	return fmt.Errorf("zuora: %s %s: %s", method, path, strings.Join(messages, "; "))
+}
+
+const communicationProfilePath = "/v1/object/communication-profile"
+
+// CreateCommunicationProfile creates the communication profile and returns its ID
+func (c *Client) CreateCommunicationProfile(profile CommunicationProfile) (string, error) {
+	var body objectResponse
+	if err := c.do("POST", communicationProfilePath, profile, &body); err != nil {
+		return "", err
+	}
+
+	return body.ID, body.err("POST", communicationProfilePath)
+}
+
+// UpdateCommunicationProfile updates the communication profile with the given ID
+func (c *Client) UpdateCommunicationProfile(id string, profile CommunicationProfile) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	// the object API rejects the ID in the body
+	profile.ID = ""
+
+	var body objectResponse
+	path := communicationProfilePath + "/" + id
+	if err := c.do("PUT", path, profile, &body); err != nil {
+		return err
+	}
+
+	return body.err("PUT", path)
+}
+
+// DeleteCommunicationProfile deletes the communication profile with the given ID
+func (c *Client) DeleteCommunicationProfile(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	var body objectResponse
+	path := communicationProfilePath + "/" + id
+	if err := c.do("DELETE", path, nil, &body); err != nil {
+		return err
+	}
+
+	return body.err("DELETE", path)
+}
@@ -0,0 +1,100 @@
+package zuora
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the idempotent requests are retried when Zuora
+// responds with 429 Too Many Requests or a 5xx status code
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+
+	// MinWait is the base of the exponential backoff
+	MinWait time.Duration
+
+	// MaxWait caps the exponential backoff, it does not apply to the delays
+	// requested by Zuora with the Retry-After or rate limit headers
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy is used by the clients returned by NewClient
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	MinWait:    500 * time.Millisecond,
+	MaxWait:    30 * time.Second,
+}
+
+// idempotent returns true for the HTTP methods safe to send twice
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable returns true when the response status code is worth a retry
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// backoff returns the exponential backoff with full jitter for the attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := float64(p.MinWait) * math.Pow(2, float64(attempt))
+	if ceiling > float64(p.MaxWait) {
+		ceiling = float64(p.MaxWait)
+	}
+
+	if ceiling < 1 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// delay returns how long to wait before the next attempt, honouring the
+// Retry-After and Zuora rate limit headers when present
+func (p RetryPolicy) delay(response *http.Response, attempt int) time.Duration {
+	if response != nil {
+		if d, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+			return d
+		}
+
+		if response.StatusCode == http.StatusTooManyRequests {
+			for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+				if seconds, err := strconv.Atoi(response.Header.Get(header)); err == nil && seconds >= 0 {
+					return time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	}
+
+	return p.backoff(attempt)
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
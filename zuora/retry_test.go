@@ -0,0 +1,84 @@
+package zuora
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	t.Run("idempotent requests are retried honouring Retry-After", func(t *testing.T) {
+		waits = nil
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			switch calls {
+			case 1:
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				fmt.Fprint(w, `{"data": [{"id": "1"}]}`)
+			}
+		}))
+		defer server.Close()
+
+		got, err := NewClient(server.URL, staticToken("secret")).ListEventTriggers()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 1 || calls != 3 {
+			t.Errorf("got %v after %d calls want 1 trigger after 3 calls", got, calls)
+		}
+
+		if len(waits) != 2 || waits[0] != 7*time.Second || waits[1] > DefaultRetryPolicy.MinWait*2 {
+			t.Errorf("waits: got %v", waits)
+		}
+	})
+
+	t.Run("non-idempotent requests are not retried", func(t *testing.T) {
+		waits = nil
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := NewClient(server.URL, staticToken("secret")).CreateEventTrigger(EventTrigger{})
+		if err == nil || calls != 1 {
+			t.Errorf("got %v after %d calls want an error after 1 call", err, calls)
+		}
+	})
+
+	t.Run("gives up after the max retries", func(t *testing.T) {
+		waits = nil
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("X-RateLimit-Reset", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, staticToken("secret"))
+		client.Retry.MaxRetries = 2
+
+		err := client.DeleteEventTrigger("1")
+		if zerr, ok := err.(*Error); !ok || zerr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("got %v want a 429 error", err)
+		}
+
+		if calls != 3 || len(waits) != 2 || waits[0] != 3*time.Second {
+			t.Errorf("got %d calls and waits %v", calls, waits)
+		}
+	})
+}
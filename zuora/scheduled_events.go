@@ -0,0 +1,77 @@
+package zuora
+
+// ScheduledEventParameter is a parameter of the scheduled event condition,
+// valued by the notifications
+type ScheduledEventParameter struct {
+	Description string   `json:"description,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Options     []string `json:"options,omitempty"`
+	ValueType   string   `json:"valueType"`
+}
+
+// ScheduledEvent fires daily at the given time for the records of the base
+// object matching the condition
+type ScheduledEvent struct {
+	ID          string                             `json:"id,omitempty"`
+	Active      bool                               `json:"active"`
+	APIField    string                             `json:"apiField"`
+	APIObject   string                             `json:"apiObject"`
+	Condition   string                             `json:"condition"`
+	Description string                             `json:"description"`
+	DisplayName string                             `json:"displayName"`
+	Hours       int                                `json:"hours"`
+	Minutes     int                                `json:"minutes"`
+	Name        string                             `json:"name"`
+	Parameters  map[string]ScheduledEventParameter `json:"parameters,omitempty"`
+}
+
+type scheduledEventsResponse struct {
+	Data []ScheduledEvent `json:"data"`
+	Next string           `json:"next"`
+}
+
+const scheduledEventsPath = "/events/scheduled-events"
+
+// ListScheduledEvents returns every scheduled event, following the pagination
+func (c *Client) ListScheduledEvents() ([]ScheduledEvent, error) {
+	result := make([]ScheduledEvent, 0)
+
+	for path := scheduledEventsPath; path != ""; {
+		var body scheduledEventsResponse
+		if err := c.do("GET", path, nil, &body); err != nil {
+			return nil, err
+		}
+
+		result = append(result, body.Data...)
+		path = body.Next
+	}
+
+	return result, nil
+}
+
+// CreateScheduledEvent creates the scheduled event and returns it with its ID
+func (c *Client) CreateScheduledEvent(event ScheduledEvent) (ScheduledEvent, error) {
+	var created ScheduledEvent
+	err := c.do("POST", scheduledEventsPath, event, &created)
+	return created, err
+}
+
+// UpdateScheduledEvent replaces the scheduled event with the given ID
+func (c *Client) UpdateScheduledEvent(id string, event ScheduledEvent) (ScheduledEvent, error) {
+	if id == "" {
+		return ScheduledEvent{}, ErrMissingID
+	}
+
+	var updated ScheduledEvent
+	err := c.do("PUT", scheduledEventsPath+"/"+id, event, &updated)
+	return updated, err
+}
+
+// DeleteScheduledEvent deletes the scheduled event with the given ID
+func (c *Client) DeleteScheduledEvent(id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do("DELETE", scheduledEventsPath+"/"+id, nil, nil)
+}